@@ -5,10 +5,14 @@ import (
 	"net/http"
 	"net/url"
 	"strings"
+	"sync"
 	"time"
 
+	"go.uber.org/zap"
+
 	"github.com/onedr0p/exportarr/internal/arr/config"
 	"github.com/onedr0p/exportarr/internal/client"
+	base_config "github.com/onedr0p/exportarr/internal/config"
 	base_client "github.com/onedr0p/exportarr/internal/client"
 )
 
@@ -20,24 +24,68 @@ func NewClient(config *config.ArrConfig) (*Client, error) {
 	if err != nil {
 		return nil, err
 	}
-	return base_client.NewClient(config.BaseURL(), config.DisableSSLVerify, auth, config.ApiRootPath)
+	opts, err := transportOptions(config)
+	if err != nil {
+		return nil, err
+	}
+	c, err := base_client.NewClient(config.BaseURL(), opts, auth, config.ApiRootPath)
+	if err != nil {
+		return nil, err
+	}
+	c.App = config.App
+	return c, nil
+}
+
+// transportOptions returns how the target described by config is connected to.
+func transportOptions(config *config.ArrConfig) (client.TransportOptions, error) {
+	tlsConfig, err := config.TLS().Build()
+	if err != nil {
+		return client.TransportOptions{}, err
+	}
+	proxy, err := base_config.ParseProxyURL(config.ProxyURL)
+	if err != nil {
+		return client.TransportOptions{}, err
+	}
+	return client.TransportOptions{TLS: tlsConfig, Proxy: proxy, Retry: config.RetryPolicy}, nil
+}
+
+// SharedClient returns the long-lived client of the target described by config
+// from config.Clients, creating it on first use. Without a pool, e.g. for
+// /probe requests, a new client is returned.
+func SharedClient(config *config.ArrConfig) (*Client, error) {
+	return config.Clients.Get(config, func() (*Client, error) {
+		return NewClient(config)
+	})
 }
 
 func NewAuth(config *config.ArrConfig) (client.Authenticator, error) {
 	var auth client.Authenticator
+	opts, err := transportOptions(config)
+	if err != nil {
+		return nil, err
+	}
+	proxyAuth := client.ProxyAuth(config.Headers, client.OAuth2Config{
+		TokenURL:     config.OAuth2TokenURL,
+		ClientID:     config.OAuth2ClientID,
+		ClientSecret: config.OAuth2Secret,
+		Scopes:       config.OAuth2Scopes,
+	}, opts)
 
 	if config.UseFormAuth() {
-		u, err := url.Parse(config.URL)
+		// Logins go to the socket of a unix:// URL like the API requests
+		u, socket, err := client.SocketURL(config.URL)
 		if err != nil {
 			return nil, err
 		}
+		opts.Socket = socket
 		auth = &FormAuth{
 			Username:    config.AuthUsername,
 			Password:    config.AuthPassword,
 			ApiKey:      config.ApiKey,
 			ApiRootPath: config.ApiRootPath,
 			AuthBaseURL: u,
-			Transport:   client.BaseTransport(config.DisableSSLVerify),
+			Transport:   client.AuthTransport(client.BaseTransport(opts), proxyAuth),
+			App:         config.App,
 		}
 	} else if config.UseBasicAuth() {
 		auth = &BasicAuth{
@@ -53,7 +101,7 @@ func NewAuth(config *config.ArrConfig) (client.Authenticator, error) {
 			ApiRootPath: config.ApiRootPath,
 		}
 	}
-	return auth, nil
+	return client.Chain(auth, proxyAuth), nil
 }
 
 type ApiKeyAuth struct {
@@ -79,6 +127,13 @@ func (a *BasicAuth) Auth(req *http.Request) error {
 	return nil
 }
 
+// FORM_AUTH_RENEWAL_MARGIN is how long before the session cookie expires a new
+// session is logged in, so requests in flight don't fail.
+var FORM_AUTH_RENEWAL_MARGIN = time.Hour
+
+// FormAuth logs in to the app's login form, sharing the session between every
+// collector of the target. The session is renewed shortly before it expires and
+// whenever the app rejects it.
 type FormAuth struct {
 	Username    string
 	Password    string
@@ -86,62 +141,135 @@ type FormAuth struct {
 	ApiRootPath string
 	AuthBaseURL *url.URL
 	Transport   http.RoundTripper
-	cookie      *http.Cookie
+	App         string       // App logins are counted for
+	cookie      *http.Cookie // nil until logged in or once rejected
+	expiry      time.Time    // When the cookie expires, zero when it lasts the session
+	renewAt     time.Time    // When to log in again ahead of the expiry
+	rejected    bool         // Whether the app rejected the last cookie
+	mutex       sync.Mutex
 }
 
 func (a *FormAuth) Auth(req *http.Request) error {
-	if a.cookie == nil || a.cookie.Expires.Before(time.Now().Add(-5*time.Minute)) {
-		form := url.Values{
-			"username":   {a.Username},
-			"password":   {a.Password},
-			"rememberMe": {"on"},
+	// Only one of the collectors sharing the session logs in.
+	a.mutex.Lock()
+	defer a.mutex.Unlock()
+
+	var reason string
+	switch {
+	case a.cookie == nil && a.rejected:
+		reason = "rejected"
+	case a.cookie == nil:
+		reason = "new"
+	case !a.renewAt.IsZero() && time.Now().After(a.renewAt):
+		reason = "expiring"
+	}
+	if reason != "" {
+		err := a.login(req)
+		base_client.ObserveLogin(a.App, reason, err)
+		switch {
+		case err == nil:
+			a.rejected = false
+		case a.cookie != nil && time.Now().Before(a.expiry):
+			// The current session can still be used while the renewal is retried.
+			a.renewAt = time.Now().Add(time.Minute)
+			zap.S().Warnw("Failed to renew FormAuth session, using the current one until it expires",
+				"app", a.App,
+				"expiry", a.expiry,
+				"error", err)
+		default:
+			return err
 		}
+	}
 
-		u := a.AuthBaseURL.JoinPath("login")
-		vals := u.Query()
-		vals.Add("ReturnUrl", "/general/settings")
-		u.RawQuery = vals.Encode()
+	req.AddCookie(a.cookie)
+	req.Header.Add("X-Api-Key", a.ApiKey)
 
-		authReq, err := http.NewRequest("POST", u.String(), strings.NewReader(form.Encode()))
-		if err != nil {
-			return fmt.Errorf("Failed to renew FormAuth Cookie: %w", err)
-		}
+	return nil
+}
 
-		authReq.Header.Add("Content-Type", "application/x-www-form-urlencoded")
-		authReq.Header.Add("Content-Length", fmt.Sprintf("%d", len(form.Encode())))
+// Invalidate drops the session req was sent with, once the app rejected it.
+// Sessions renewed by concurrent requests in the meantime are kept.
+func (a *FormAuth) Invalidate(req *http.Request) {
+	a.mutex.Lock()
+	defer a.mutex.Unlock()
+	if a.cookie == nil {
+		return
+	}
+	if cookie, err := req.Cookie(a.cookie.Name); err == nil && cookie.Value == a.cookie.Value {
+		a.cookie = nil
+		a.rejected = true
+	}
+}
 
-		client := &http.Client{Transport: a.Transport, CheckRedirect: func(req *http.Request, via []*http.Request) error {
-			if req.URL.Query().Get("loginFailed") == "true" {
-				return fmt.Errorf("Failed to renew FormAuth Cookie: Login Failed")
-			}
-			return http.ErrUseLastResponse
-		}}
+// login logs in with the credentials, storing the session cookie.
+func (a *FormAuth) login(req *http.Request) error {
+	form := url.Values{
+		"username":   {a.Username},
+		"password":   {a.Password},
+		"rememberMe": {"on"},
+	}
 
-		authResp, err := client.Do(authReq)
-		if err != nil {
-			return fmt.Errorf("Failed to renew FormAuth Cookie: %w", err)
-		}
+	u := a.AuthBaseURL.JoinPath("login")
+	vals := u.Query()
+	vals.Add("ReturnUrl", "/general/settings")
+	u.RawQuery = vals.Encode()
+
+	authReq, err := http.NewRequestWithContext(req.Context(), "POST", u.String(), strings.NewReader(form.Encode()))
+	if err != nil {
+		return fmt.Errorf("Failed to renew FormAuth Cookie: %w", err)
+	}
+
+	authReq.Header.Add("Content-Type", "application/x-www-form-urlencoded")
+	authReq.Header.Add("Content-Length", fmt.Sprintf("%d", len(form.Encode())))
 
-		if authResp.StatusCode != 302 {
-			return fmt.Errorf("Failed to renew FormAuth Cookie: Received Status Code %d", authResp.StatusCode)
+	client := &http.Client{Transport: a.Transport, CheckRedirect: func(req *http.Request, via []*http.Request) error {
+		if req.URL.Query().Get("loginFailed") == "true" {
+			return fmt.Errorf("Failed to renew FormAuth Cookie: Login Failed")
 		}
+		return http.ErrUseLastResponse
+	}}
 
-		found := false
-		for _, cookie := range authResp.Cookies() {
-			if strings.HasSuffix(cookie.Name, "arrAuth") {
-				copy := *cookie
-				a.cookie = &copy
-				found = true
-				break
+	authResp, err := client.Do(authReq)
+	if err != nil {
+		return fmt.Errorf("Failed to renew FormAuth Cookie: %w", err)
+	}
+	defer authResp.Body.Close()
+
+	if authResp.StatusCode != 302 {
+		return fmt.Errorf("Failed to renew FormAuth Cookie: Received Status Code %d", authResp.StatusCode)
+	}
+
+	for _, cookie := range authResp.Cookies() {
+		if strings.HasSuffix(cookie.Name, "arrAuth") {
+			copy := *cookie
+			a.cookie = &copy
+			a.expiry = cookieExpiry(cookie)
+			a.renewAt = time.Time{}
+			if !a.expiry.IsZero() {
+				// Short lived sessions are renewed half way through instead.
+				margin := FORM_AUTH_RENEWAL_MARGIN
+				if half := time.Until(a.expiry) / 2; half < margin {
+					margin = half
+				}
+				a.renewAt = a.expiry.Add(-margin)
 			}
-		}
-		if !found {
-			return fmt.Errorf("Failed to renew FormAuth Cookie: No Cookie with suffix 'arrAuth' found")
+			return nil
 		}
 	}
+	return fmt.Errorf("Failed to renew FormAuth Cookie: No Cookie with suffix 'arrAuth' found")
+}
 
-	req.AddCookie(a.cookie)
-	req.Header.Add("X-Api-Key", a.ApiKey)
+// CloseIdleConnections closes the idle connections logins were sent over.
+func (a *FormAuth) CloseIdleConnections() {
+	if c, ok := a.Transport.(interface{ CloseIdleConnections() }); ok {
+		c.CloseIdleConnections()
+	}
+}
 
-	return nil
+// cookieExpiry returns when cookie expires, zero when it lasts the session.
+func cookieExpiry(cookie *http.Cookie) time.Time {
+	if cookie.MaxAge > 0 {
+		return time.Now().Add(time.Duration(cookie.MaxAge) * time.Second)
+	}
+	return cookie.Expires
 }
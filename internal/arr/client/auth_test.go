@@ -1,14 +1,20 @@
 package client
 
 import (
+	"context"
 	"encoding/base64"
 	"fmt"
+	"net"
 	"net/http"
 	"net/http/httptest"
 	"net/url"
+	"path/filepath"
+	"sync"
+	"sync/atomic"
 	"testing"
 	"time"
 
+	"github.com/onedr0p/exportarr/internal/arr/config"
 	base_client "github.com/onedr0p/exportarr/internal/client"
 
 	"github.com/stretchr/testify/require"
@@ -235,3 +241,238 @@ func TestRoundTrip_StatusCodes(t *testing.T) {
 		})
 	}
 }
+
+func TestSharedClient(t *testing.T) {
+	require := require.New(t)
+	c := &config.ArrConfig{
+		App:        "sonarr",
+		ApiVersion: "v3",
+		URL:        "http://shared.example.com",
+		ApiKey:     TEST_KEY,
+		Clients:    base_client.NewPool(),
+	}
+	first, err := SharedClient(c)
+	require.NoError(err)
+	second, err := SharedClient(c)
+	require.NoError(err)
+	require.Same(first, second, "Collectors of the same target should share a client")
+
+	other := *c
+	other.ApiKey = "abcdef0123456789abcdef0123456789"
+	otherClient, err := SharedClient(&other)
+	require.NoError(err)
+	require.NotSame(first, otherClient, "Each target should have its own client")
+
+	probe := *c
+	probe.Clients = nil
+	probeClient, err := SharedClient(&probe)
+	require.NoError(err)
+	again, err := SharedClient(&probe)
+	require.NoError(err)
+	require.NotSame(probeClient, again, "Clients without a pool shouldn't be cached")
+}
+
+func TestNewAuth_FormAuthTLS(t *testing.T) {
+	require := require.New(t)
+	c := &config.ArrConfig{
+		App:           "sonarr",
+		URL:           "https://sonarr.example.com",
+		ApiKey:        TEST_KEY,
+		AuthUsername:  TEST_USER,
+		AuthPassword:  TEST_PASS,
+		FormAuth:      true,
+		TLSServerName: "sonarr.internal",
+	}
+	auth, err := NewAuth(c)
+	require.NoError(err)
+	transport := auth.(*FormAuth).Transport.(*http.Transport)
+	require.Equal("sonarr.internal", transport.TLSClientConfig.ServerName, "The login should use the TLS settings of the client")
+
+	c.TLSPinSHA256 = []string{"not-a-fingerprint"}
+	_, err = NewAuth(c)
+	require.Error(err)
+}
+
+func TestNewAuth_ProxyAuth(t *testing.T) {
+	require := require.New(t)
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		require.Equal("service-token", r.Header.Get("Cf-Access-Client-Secret"), "The login should pass the proxy")
+		http.SetCookie(w, &http.Cookie{
+			Name:    "SonarrAuth",
+			Value:   "abcdef1234567890abcdef1234567890",
+			Expires: time.Now().Add(24 * time.Hour),
+		})
+		w.WriteHeader(http.StatusFound)
+	}))
+	defer ts.Close()
+
+	c := &config.ArrConfig{
+		App:          "sonarr",
+		URL:          ts.URL,
+		ApiKey:       TEST_KEY,
+		AuthUsername: TEST_USER,
+		AuthPassword: TEST_PASS,
+		FormAuth:     true,
+		Headers:      map[string]string{"CF-Access-Client-Secret": "service-token"},
+	}
+	auth, err := NewAuth(c)
+	require.NoError(err)
+	req, err := http.NewRequest("GET", "http://example.com", nil)
+	require.NoError(err)
+	require.NoError(auth.Auth(req))
+	require.Equal(TEST_KEY, req.Header.Get("X-Api-Key"))
+	require.Equal("service-token", req.Header.Get("Cf-Access-Client-Secret"))
+	cookie, err := req.Cookie("SonarrAuth")
+	require.NoError(err)
+	require.Equal("abcdef1234567890abcdef1234567890", cookie.Value)
+
+	c.FormAuth = false
+	c.AuthUsername, c.AuthPassword = "", ""
+	c.Headers = nil
+	auth, err = NewAuth(c)
+	require.NoError(err)
+	require.IsType(&ApiKeyAuth{}, auth, "No proxy auth should be added when none is configured")
+}
+
+// sessionServer is an app whose login hands out a new session, only the latest of which is accepted.
+func sessionServer(logins *atomic.Int32, session *atomic.Value) *httptest.Server {
+	return httptest.NewServer(sessionHandler(logins, session))
+}
+
+func sessionHandler(logins *atomic.Int32, session *atomic.Value) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/login" {
+			value := fmt.Sprintf("session-%d", logins.Add(1))
+			session.Store(value)
+			http.SetCookie(w, &http.Cookie{Name: "SonarrAuth", Value: value, MaxAge: 86400})
+			w.WriteHeader(http.StatusFound)
+			return
+		}
+		cookie, err := r.Cookie("SonarrAuth")
+		if err != nil || cookie.Value != session.Load().(string) {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		w.Write([]byte(`{}`))
+	})
+}
+
+func TestFormAuth_Socket(t *testing.T) {
+	require := require.New(t)
+	var logins atomic.Int32
+	var session atomic.Value
+	socket := filepath.Join(t.TempDir(), "sonarr.sock")
+	listener, err := net.Listen("unix", socket)
+	require.NoError(err)
+	ts := httptest.NewUnstartedServer(sessionHandler(&logins, &session))
+	ts.Listener.Close()
+	ts.Listener = listener
+	ts.Start()
+	defer ts.Close()
+
+	c, err := NewClient(&config.ArrConfig{
+		App:          "sonarr",
+		ApiVersion:   "v3",
+		URL:          "unix://" + socket,
+		ApiKey:       TEST_KEY,
+		AuthUsername: TEST_USER,
+		AuthPassword: TEST_PASS,
+		FormAuth:     true,
+	})
+	require.NoError(err)
+	var target map[string]interface{}
+	require.NoError(c.DoRequest(context.Background(), "system/status", &target))
+	require.Equal(int32(1), logins.Load(), "The login should be sent over the socket")
+}
+
+func TestFormAuth_Relogin(t *testing.T) {
+	require := require.New(t)
+	var logins atomic.Int32
+	var session atomic.Value
+	ts := sessionServer(&logins, &session)
+	defer ts.Close()
+
+	c, err := NewClient(&config.ArrConfig{
+		App:          "sonarr",
+		ApiVersion:   "v3",
+		URL:          ts.URL,
+		ApiKey:       TEST_KEY,
+		AuthUsername: TEST_USER,
+		AuthPassword: TEST_PASS,
+		FormAuth:     true,
+	})
+	require.NoError(err)
+	var target map[string]interface{}
+	require.NoError(c.DoRequest(context.Background(), "system/status", &target))
+	require.Equal(int32(1), logins.Load())
+
+	// The app forgets the session, e.g. when it restarts
+	session.Store("")
+	var wg sync.WaitGroup
+	for i := 0; i < 10; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			var target map[string]interface{}
+			require.NoError(c.DoRequest(context.Background(), "system/status", &target))
+		}()
+	}
+	wg.Wait()
+	require.Equal(int32(2), logins.Load(), "Concurrent requests should log in again only once")
+}
+
+func TestFormAuth_Renewal(t *testing.T) {
+	require := require.New(t)
+	var logins atomic.Int32
+	var session atomic.Value
+	ts := sessionServer(&logins, &session)
+	defer ts.Close()
+	tsUrl, _ := url.Parse(ts.URL)
+	auth := &FormAuth{
+		Username:    TEST_USER,
+		Password:    TEST_PASS,
+		ApiKey:      TEST_KEY,
+		AuthBaseURL: tsUrl,
+		Transport:   http.DefaultTransport,
+	}
+
+	for i := 0; i < 3; i++ {
+		req, err := http.NewRequest("GET", ts.URL, nil)
+		require.NoError(err)
+		require.NoError(auth.Auth(req))
+	}
+	require.Equal(int32(1), logins.Load(), "The session should be reused")
+	require.WithinDuration(time.Now().Add(24*time.Hour-FORM_AUTH_RENEWAL_MARGIN), auth.renewAt, time.Minute)
+
+	// Sessions are renewed before they expire
+	auth.renewAt = time.Now().Add(-time.Second)
+	req, err := http.NewRequest("GET", ts.URL, nil)
+	require.NoError(err)
+	require.NoError(auth.Auth(req))
+	require.Equal(int32(2), logins.Load())
+	cookie, err := req.Cookie("SonarrAuth")
+	require.NoError(err)
+	require.Equal("session-2", cookie.Value)
+
+	// A failed renewal keeps the current session until it expires
+	auth.renewAt = time.Now().Add(-time.Second)
+	auth.Password = "wrong"
+	ts.Close()
+	req, err = http.NewRequest("GET", ts.URL, nil)
+	require.NoError(err)
+	require.NoError(auth.Auth(req))
+	cookie, err = req.Cookie("SonarrAuth")
+	require.NoError(err)
+	require.Equal("session-2", cookie.Value)
+	require.True(auth.renewAt.After(time.Now()), "The renewal should be retried later")
+
+	// Only the rejected session is dropped
+	stale, err := http.NewRequest("GET", ts.URL, nil)
+	require.NoError(err)
+	stale.AddCookie(&http.Cookie{Name: "SonarrAuth", Value: "session-1"})
+	auth.Invalidate(stale)
+	require.NotNil(auth.cookie)
+	auth.Invalidate(req)
+	require.Nil(auth.cookie)
+	require.Error(auth.Auth(req), "Without a session the login must succeed")
+}
@@ -0,0 +1,45 @@
+package client
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/onedr0p/exportarr/internal/arr/config"
+	"github.com/onedr0p/exportarr/internal/arr/model"
+)
+
+// DetectTimeout bounds how long detecting an app may delay loading or a scrape.
+var DetectTimeout = 10 * time.Second
+
+// DetectAPI detects the app behind config.URL and its version from its
+// system/status endpoint and sets them with config.SetAPI. When detection
+// fails config is left unchanged, so all of the app's features are assumed.
+func DetectAPI(ctx context.Context, c *config.ArrConfig) error {
+	if _, ok := config.ApiVersions[c.App]; !ok {
+		return nil
+	}
+	ctx, cancel := context.WithTimeout(ctx, DetectTimeout)
+	defer cancel()
+
+	// A client of its own, so detection doesn't hold on to the shared client
+	sc, err := NewClient(c)
+	if err != nil {
+		return err
+	}
+	defer sc.CloseIdleConnections()
+	status := model.SystemStatus{}
+	if err := sc.DoRequest(ctx, "system/status", &status); err != nil {
+		return fmt.Errorf("Couldn't detect app version: %w", err)
+	}
+	if app := strings.ToLower(status.AppName); app != "" && app != c.App {
+		return fmt.Errorf("%s serves %s, not %s", c.URL, status.AppName, c.App)
+	}
+
+	c.SetAPI(&config.APIInfo{
+		App:     strings.ToLower(status.AppName),
+		Version: status.Version,
+	})
+	return nil
+}
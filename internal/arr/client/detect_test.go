@@ -0,0 +1,83 @@
+package client
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/onedr0p/exportarr/internal/arr/config"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestDetectAPI(t *testing.T) {
+	parameters := []struct {
+		name   string
+		app    string
+		status string
+		api    *config.APIInfo
+		err    string
+	}{
+		{
+			name:   "sonarr",
+			app:    "sonarr",
+			status: `{"appName":"Sonarr","version":"4.0.1.929"}`,
+			api:    &config.APIInfo{App: "sonarr", Version: "4.0.1.929"},
+		},
+		{
+			name:   "lidarr",
+			app:    "lidarr",
+			status: `{"appName":"Lidarr","version":"2.0.7.3849"}`,
+			api:    &config.APIInfo{App: "lidarr", Version: "2.0.7.3849"},
+		},
+		{
+			name: "unreachable",
+			app:  "radarr",
+			err:  "Couldn't detect app version",
+		},
+		{
+			name:   "wrong app",
+			app:    "sonarr",
+			status: `{"appName":"Radarr","version":"5.0.3.8127"}`,
+			err:    "serves Radarr, not sonarr",
+		},
+	}
+	for _, p := range parameters {
+		t.Run(p.name, func(t *testing.T) {
+			require := require.New(t)
+			ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				require.Equal(TEST_KEY, r.Header.Get("X-Api-Key"))
+				if p.status == "" || r.URL.Path != fmt.Sprintf("/api/%s/system/status", config.DefaultApiVersion(p.app)) {
+					w.WriteHeader(http.StatusNotFound)
+					return
+				}
+				fmt.Fprint(w, p.status)
+			}))
+			defer ts.Close()
+
+			c := &config.ArrConfig{
+				App:        p.app,
+				URL:        ts.URL,
+				ApiKey:     TEST_KEY,
+				ApiVersion: config.DefaultApiVersion(p.app),
+			}
+			err := DetectAPI(context.Background(), c)
+			if p.err != "" {
+				require.ErrorContains(err, p.err)
+				require.Nil(c.API())
+			} else {
+				require.NoError(err)
+				require.Equal(p.api, c.API())
+			}
+		})
+	}
+}
+
+func TestDetectAPI_Bazarr(t *testing.T) {
+	require := require.New(t)
+	c := &config.ArrConfig{App: "bazarr", URL: "http://127.0.0.1:1"}
+	require.NoError(DetectAPI(context.Background(), c))
+	require.Nil(c.API())
+}
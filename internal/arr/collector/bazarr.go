@@ -1,6 +1,7 @@
 package collector
 
 import (
+	"context"
 	"fmt"
 	"sync"
 	"time"
@@ -289,8 +290,12 @@ func (collector *bazarrCollector) Describe(ch chan<- *prometheus.Desc) {
 }
 
 func (collector *bazarrCollector) Collect(ch chan<- prometheus.Metric) {
+	collector.CollectContext(context.Background(), ch)
+}
+
+func (collector *bazarrCollector) CollectContext(ctx context.Context, ch chan<- prometheus.Metric) {
 	log := zap.S().With("collector", "bazarr")
-	c, err := client.NewClient(collector.config)
+	c, err := client.SharedClient(collector.config)
 	if err != nil {
 		log.Errorw("Error creating client", "error", err)
 		ch <- prometheus.NewInvalidMetric(collector.errorMetric, err)
@@ -298,24 +303,24 @@ func (collector *bazarrCollector) Collect(ch chan<- prometheus.Metric) {
 	}
 	tseries := time.Now()
 
-	collector.EpisodeMovieMetrics(ch, c)
-	collector.SystemMetrics(ch, c)
+	collector.EpisodeMovieMetrics(ctx, ch, c)
+	collector.SystemMetrics(ctx, ch, c)
 
 	mt := time.Since(tseries)
 	log.Debugw("All Completed", "duration", mt)
 }
 
-func (collector *bazarrCollector) EpisodeMovieMetrics(ch chan<- prometheus.Metric, c *client.Client) {
+func (collector *bazarrCollector) EpisodeMovieMetrics(ctx context.Context, ch chan<- prometheus.Metric, c *client.Client) {
 
 	episodeStats := newStats()
 	if collector.config.EnableAdditionalMetrics {
-		episodeStats = collector.CollectEpisodeStats(ch, c)
+		episodeStats = collector.CollectEpisodeStats(ctx, ch, c)
 		if episodeStats == nil {
 			return
 		}
 	}
 
-	movieStats := collector.CollectMovieStats(ch, c)
+	movieStats := collector.CollectMovieStats(ctx, ch, c)
 	if movieStats == nil {
 		return
 	}
@@ -392,14 +397,14 @@ func (collector *bazarrCollector) EpisodeMovieMetrics(ch chan<- prometheus.Metri
 	}
 }
 
-func (collector *bazarrCollector) CollectEpisodeStats(ch chan<- prometheus.Metric, c *client.Client) *stats {
+func (collector *bazarrCollector) CollectEpisodeStats(ctx context.Context, ch chan<- prometheus.Metric, c *client.Client) *stats {
 	log := zap.S().With("collector", "bazarr")
 	episodeStats := newStats()
 
 	mseries := time.Now()
 
 	series := model.BazarrSeries{}
-	if err := c.DoRequest("series", &series); err != nil {
+	if err := c.DoRequest(ctx, "series", &series); err != nil {
 		log.Errorw("Error getting series",
 			"error", err)
 		ch <- prometheus.NewInvalidMetric(collector.errorMetric, err)
@@ -412,7 +417,7 @@ func (collector *bazarrCollector) CollectEpisodeStats(ch chan<- prometheus.Metri
 	}
 	batches := createIDBatches(ids, collector.config.Bazarr.SeriesBatchSize)
 
-	eg := errgroup.Group{}
+	eg, egCtx := errgroup.WithContext(ctx)
 	sem := make(chan int, collector.config.Bazarr.SeriesBatchConcurrency) // limit concurrency via semaphore
 	for _, batch := range batches {
 		params := client.QueryParams{"seriesid[]": batch}
@@ -420,7 +425,7 @@ func (collector *bazarrCollector) CollectEpisodeStats(ch chan<- prometheus.Metri
 		eg.Go(func() error {
 			defer func() { <-sem }()
 			episodes := model.BazarrEpisodes{}
-			if err := c.DoRequest("episodes", &episodes, params); err != nil {
+			if err := c.DoRequest(egCtx, "episodes", &episodes, params); err != nil {
 				return err
 			}
 			episodeStats.mut.Lock()
@@ -466,7 +471,7 @@ func (collector *bazarrCollector) CollectEpisodeStats(ch chan<- prometheus.Metri
 	}
 
 	history := model.BazarrHistory{}
-	if err := c.DoRequest("episodes/history", &history); err != nil {
+	if err := c.DoRequest(ctx, "episodes/history", &history); err != nil {
 		log.Errorw("Error getting episodes history",
 			"error", err)
 		ch <- prometheus.NewInvalidMetric(collector.errorMetric, err)
@@ -489,7 +494,7 @@ func (collector *bazarrCollector) CollectEpisodeStats(ch chan<- prometheus.Metri
 	return episodeStats
 }
 
-func (collector *bazarrCollector) CollectMovieStats(ch chan<- prometheus.Metric, c *client.Client) *stats {
+func (collector *bazarrCollector) CollectMovieStats(ctx context.Context, ch chan<- prometheus.Metric, c *client.Client) *stats {
 	log := zap.S().With("collector", "bazarr")
 	mseries := time.Now()
 	movieStats := new(stats)
@@ -498,7 +503,7 @@ func (collector *bazarrCollector) CollectMovieStats(ch chan<- prometheus.Metric,
 	movieStats.providers = make(map[string]int)
 
 	movies := model.BazarrMovies{}
-	if err := c.DoRequest("movies", &movies); err != nil {
+	if err := c.DoRequest(ctx, "movies", &movies); err != nil {
 		log.Errorw("Error getting subtitles", "error", err)
 		ch <- prometheus.NewInvalidMetric(collector.errorMetric, err)
 		return nil
@@ -538,7 +543,7 @@ func (collector *bazarrCollector) CollectMovieStats(ch chan<- prometheus.Metric,
 
 	// Bazarr keeps separate histories for TV vs Movies, and therefore cannot leverage the shared HistoryCollector.
 	history := model.BazarrHistory{}
-	if err := c.DoRequest("movies/history", &history); err != nil {
+	if err := c.DoRequest(ctx, "movies/history", &history); err != nil {
 		log.Errorw("Error getting movies history",
 			"error", err)
 		ch <- prometheus.NewInvalidMetric(collector.errorMetric, err)
@@ -562,11 +567,11 @@ func (collector *bazarrCollector) CollectMovieStats(ch chan<- prometheus.Metric,
 	return movieStats
 }
 
-func (collector *bazarrCollector) SystemMetrics(ch chan<- prometheus.Metric, c *client.Client) {
+func (collector *bazarrCollector) SystemMetrics(ctx context.Context, ch chan<- prometheus.Metric, c *client.Client) {
 	log := zap.S().With("collector", "bazarr")
 
 	health := model.BazarrHealth{}
-	if err := c.DoRequest("system/health", &health); err != nil {
+	if err := c.DoRequest(ctx, "system/health", &health); err != nil {
 		log.Errorw("Error getting movies history",
 			"error", err)
 		ch <- prometheus.NewInvalidMetric(collector.errorMetric, err)
@@ -587,7 +592,7 @@ func (collector *bazarrCollector) SystemMetrics(ch chan<- prometheus.Metric, c *
 
 	// Bazarr uses it's own status format. and therefore cannot leverage the shared StatusCollector
 	systemStatus := model.BazarrStatus{}
-	if err := c.DoRequest("system/status", &systemStatus); err != nil {
+	if err := c.DoRequest(ctx, "system/status", &systemStatus); err != nil {
 		ch <- prometheus.MustNewConstMetric(collector.systemStatusMetric, prometheus.GaugeValue, float64(0.0))
 		log.Errorw("Error getting system status", "error", err)
 	} else {
@@ -0,0 +1,149 @@
+package collector
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+
+	"github.com/onedr0p/exportarr/internal/arr/client"
+	"github.com/onedr0p/exportarr/internal/arr/config"
+	"github.com/onedr0p/exportarr/internal/arr/model"
+	"github.com/prometheus/client_golang/prometheus"
+	"go.uber.org/zap"
+)
+
+// The parameters including the quality profile and file of cutoff unmet items of each app.
+var cutoffParams = map[string][]string{
+	"sonarr":  {"includeSeries", "includeEpisodeFile"},
+	"lidarr":  {"includeArtist"},
+	"readarr": {"includeAuthor"},
+}
+
+// The apps whose cutoff unmet items include their file, and so their current quality.
+// Lidarr albums and Readarr books don't, so they aren't labelled by quality.
+var cutoffQualityApps = map[string]bool{
+	"sonarr": true,
+	"radarr": true,
+}
+
+type cutoffCollector struct {
+	config       *config.ArrConfig // App configuration
+	cutoffMetric *prometheus.Desc  // Total number of items below their quality cutoff
+	errorMetric  *prometheus.Desc  // Error Description for use with InvalidMetric
+}
+
+func NewCutoffCollector(c *config.ArrConfig) *cutoffCollector {
+	help := "Total number of items below the cutoff of their quality profile by quality_profile"
+	labels := []string{"quality_profile"}
+	if cutoffQualityApps[c.App] {
+		help += " and current quality"
+		labels = append(labels, "quality")
+	}
+	return &cutoffCollector{
+		config: c,
+		cutoffMetric: prometheus.NewDesc(
+			fmt.Sprintf("%s_cutoff_unmet_total", c.App),
+			help,
+			labels,
+			prometheus.Labels{"url": c.URL},
+		),
+		errorMetric: prometheus.NewDesc(
+			fmt.Sprintf("%s_cutoff_collector_error", c.App),
+			"Error while collecting metrics",
+			nil,
+			prometheus.Labels{"url": c.URL},
+		),
+	}
+}
+
+func (collector *cutoffCollector) Describe(ch chan<- *prometheus.Desc) {
+	ch <- collector.cutoffMetric
+}
+
+func (collector *cutoffCollector) Collect(ch chan<- prometheus.Metric) {
+	collector.CollectContext(context.Background(), ch)
+}
+
+func (collector *cutoffCollector) CollectContext(ctx context.Context, ch chan<- prometheus.Metric) {
+	log := zap.S().With("collector", "cutoff")
+	if !collector.config.Supports(config.FeatureWantedCutoff) {
+		log.Debugw("Skipping cutoff unmet items, the app doesn't support them",
+			"app", collector.config.App)
+		return
+	}
+	c, err := client.SharedClient(collector.config)
+	if err != nil {
+		log.Errorw("Error creating client",
+			"error", err)
+		ch <- prometheus.NewInvalidMetric(collector.errorMetric, err)
+		return
+	}
+
+	profiles := model.QualityProfile{}
+	if err := c.DoRequest(ctx, "qualityprofile", &profiles); err != nil {
+		log.Errorw("Error getting quality profiles",
+			"error", err)
+		ch <- prometheus.NewInvalidMetric(collector.errorMetric, err)
+		return
+	}
+	profileNames := make(map[int]string, len(profiles))
+	for _, p := range profiles {
+		profileNames[p.ID] = p.Name
+	}
+
+	params := client.QueryParams{}
+	params.Add("pageSize", "250")
+	for _, p := range cutoffParams[collector.config.App] {
+		params.Add(p, "true")
+	}
+	cutoff, err := getAllPages[model.CutoffUnmet](ctx, c, "wanted/cutoff", params)
+	if err != nil {
+		log.Errorw("Error getting cutoff unmet items",
+			"error", err)
+		ch <- prometheus.NewInvalidMetric(collector.errorMetric, err)
+		return
+	}
+
+	type key struct{ profile, quality string }
+	counts := map[key]int{}
+	for _, r := range cutoff.Records {
+		k := key{profile: "unknown", quality: "unknown"}
+		if id := cutoffProfileID(r); id != 0 {
+			k.profile = profileNames[id]
+			if k.profile == "" {
+				k.profile = strconv.Itoa(id)
+			}
+		}
+		if f := cutoffFile(r); f != nil && f.Quality.Quality.Name != "" {
+			k.quality = f.Quality.Quality.Name
+		}
+		counts[k]++
+	}
+	withQuality := cutoffQualityApps[collector.config.App]
+	for k, count := range counts {
+		labels := []string{k.profile}
+		if withQuality {
+			labels = append(labels, k.quality)
+		}
+		ch <- prometheus.MustNewConstMetric(collector.cutoffMetric, prometheus.GaugeValue, float64(count), labels...)
+	}
+}
+
+// cutoffProfileID returns the ID of the quality profile of a cutoff unmet item,
+// set on the movie itself or on the series, artist or author of the item.
+func cutoffProfileID(r model.CutoffUnmet) int {
+	for _, o := range []*model.WantedOwner{r.Series, r.Artist, r.Author} {
+		if o != nil {
+			return o.QualityProfileID
+		}
+	}
+	return r.QualityProfileID
+}
+
+// cutoffFile returns the file of a cutoff unmet item, if the app includes it.
+func cutoffFile(r model.CutoffUnmet) *model.WantedFile {
+	if r.EpisodeFile != nil {
+		return r.EpisodeFile
+	}
+	return r.MovieFile
+}
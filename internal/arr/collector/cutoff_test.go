@@ -0,0 +1,133 @@
+package collector
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/onedr0p/exportarr/internal/arr/config"
+	"github.com/onedr0p/exportarr/internal/test_util"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/stretchr/testify/require"
+)
+
+const lidarr_test_fixtures_path = "../test_fixtures/lidarr/"
+
+func TestCutoffCollect(t *testing.T) {
+	var tests = []struct {
+		name          string
+		config        *config.ArrConfig
+		fixtures_path string
+		params        []string
+	}{
+		{
+			name: "sonarr",
+			config: &config.ArrConfig{
+				App:        "sonarr",
+				ApiVersion: "v3",
+			},
+			fixtures_path: sonarr_test_fixtures_path,
+			params:        []string{"includeSeries", "includeEpisodeFile"},
+		},
+		{
+			name: "radarr",
+			config: withAPI(&config.ArrConfig{
+				App:        "radarr",
+				ApiVersion: "v3",
+			}, "5.2.6.8376"),
+			fixtures_path: radarr_test_fixtures_path,
+		},
+		{
+			name: "lidarr",
+			config: &config.ArrConfig{
+				App:        "lidarr",
+				ApiVersion: "v1",
+			},
+			fixtures_path: lidarr_test_fixtures_path,
+			params:        []string{"includeArtist"},
+		},
+		{
+			name: "readarr",
+			config: &config.ArrConfig{
+				App:        "readarr",
+				ApiVersion: "v1",
+			},
+			fixtures_path: readarr_test_fixtures_path,
+			params:        []string{"includeAuthor"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			require := require.New(t)
+			ts, err := test_util.NewTestServer(t, tt.fixtures_path, func(w http.ResponseWriter, r *http.Request) {
+				if strings.HasSuffix(r.URL.Path, "/wanted/cutoff") {
+					for _, p := range tt.params {
+						require.Equal("true", r.URL.Query().Get(p))
+					}
+				}
+			})
+			require.NoError(err)
+
+			defer ts.Close()
+
+			tt.config.URL = ts.URL
+			tt.config.ApiKey = test_util.API_KEY
+
+			collector := NewCutoffCollector(tt.config)
+
+			b, err := os.ReadFile(tt.fixtures_path + "expected_cutoff_metrics.txt")
+			require.NoError(err)
+
+			expected := strings.Replace(string(b), "SOMEURL", ts.URL, -1)
+			f := strings.NewReader(expected)
+
+			require.NotPanics(func() {
+				err = testutil.CollectAndCompare(collector, f)
+			})
+			require.NoError(err)
+		})
+	}
+}
+
+func TestCutoffCollect_Unsupported(t *testing.T) {
+	require := require.New(t)
+
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		require.Fail("no request should be made", r.URL.Path)
+	}))
+	defer ts.Close()
+
+	config := withAPI(&config.ArrConfig{
+		App:        "radarr",
+		ApiVersion: "v3",
+		URL:        ts.URL,
+		ApiKey:     test_util.API_KEY,
+	}, "4.7.5.7809")
+	require.Zero(testutil.CollectAndCount(NewCutoffCollector(config)))
+}
+
+func TestCutoffCollect_FailureDoesntPanic(t *testing.T) {
+	require := require.New(t)
+
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusInternalServerError)
+	}))
+	defer ts.Close()
+
+	config := &config.ArrConfig{
+		App:    "sonarr",
+		URL:    ts.URL,
+		ApiKey: test_util.API_KEY,
+	}
+	collector := NewCutoffCollector(config)
+
+	f := strings.NewReader("")
+
+	require.NotPanics(func() {
+		err := testutil.CollectAndCompare(collector, f)
+		require.Error(err)
+	}, "Collecting metrics should not panic on failure")
+}
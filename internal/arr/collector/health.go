@@ -1,6 +1,7 @@
 package collector
 
 import (
+	"context"
 	"fmt"
 
 	"github.com/onedr0p/exportarr/internal/arr/client"
@@ -49,15 +50,19 @@ func (collector *systemHealthCollector) Describe(ch chan<- *prometheus.Desc) {
 }
 
 func (collector *systemHealthCollector) Collect(ch chan<- prometheus.Metric) {
+	collector.CollectContext(context.Background(), ch)
+}
+
+func (collector *systemHealthCollector) CollectContext(ctx context.Context, ch chan<- prometheus.Metric) {
 	log := zap.S().With("collector", "systemHealth")
-	c, err := client.NewClient(collector.config)
+	c, err := client.SharedClient(collector.config)
 	if err != nil {
 		log.Errorf("Error creating client: %s", err)
 		ch <- prometheus.NewInvalidMetric(collector.errorMetric, err)
 		return
 	}
 	systemHealth := model.SystemHealth{}
-	if err := c.DoRequest("health", &systemHealth); err != nil {
+	if err := c.DoRequest(ctx, "health", &systemHealth); err != nil {
 		log.Errorf("Error getting health: %s", err)
 		ch <- prometheus.NewInvalidMetric(collector.errorMetric, err)
 		return
@@ -1,7 +1,12 @@
 package collector
 
 import (
+	"context"
 	"fmt"
+	"sort"
+	"strings"
+	"sync"
+	"time"
 
 	"github.com/onedr0p/exportarr/internal/arr/client"
 	"github.com/onedr0p/exportarr/internal/arr/config"
@@ -10,21 +15,92 @@ import (
 	"go.uber.org/zap"
 )
 
+// historyEventKey groups history events into the labels of the events counter.
+type historyEventKey struct {
+	eventType, protocol, indexer, downloadClient string
+}
+
+// HISTORY_CURSOR_TTL is how long the history cursor of a target no longer
+// scraped is kept.
+var HISTORY_CURSOR_TTL = time.Hour
+
+// historyCursor is how far the history of a target was counted. Collectors are
+// built again on every reload and /probe request, so the cursor outlives them:
+// events in between are still counted once, and the counters keep counting.
+type historyCursor struct {
+	mutex      sync.Mutex              // Guards the fields below, scrapes may overlap
+	lastUpdate time.Time               // Date of the last history event counted
+	lastIDs    map[int]bool            // IDs of the events counted at lastUpdate
+	events     map[historyEventKey]int // Events counted since startup or the backfill date
+	lastPoll   time.Time               // When the history was last asked for, guarded by historyCursorsMutex
+}
+
+// historyCursorKey identifies the target of a history cursor. Changing the
+// backfill settings starts counting over.
+type historyCursorKey struct {
+	app, url string
+	backfill bool
+	since    string
+}
+
+var (
+	historyCursors      = map[historyCursorKey]*historyCursor{}
+	historyCursorsMutex sync.Mutex
+)
+
+// historyCursorFor returns the history cursor of the target configured by c,
+// starting it at the backfill date, or now without backfill.
+func historyCursorFor(c *config.ArrConfig) *historyCursor {
+	historyCursorsMutex.Lock()
+	defer historyCursorsMutex.Unlock()
+	now := time.Now()
+	for key, cursor := range historyCursors {
+		if now.Sub(cursor.lastPoll) > HISTORY_CURSOR_TTL {
+			delete(historyCursors, key)
+		}
+	}
+
+	key := historyCursorKey{app: c.App, url: c.URL, backfill: c.HistoryBackfill, since: c.HistoryBackfillSince}
+	if cursor, ok := historyCursors[key]; ok {
+		return cursor
+	}
+	cursor := &historyCursor{
+		lastUpdate: now,
+		lastIDs:    map[int]bool{},
+		events:     map[historyEventKey]int{},
+		lastPoll:   now,
+	}
+	if c.HistoryBackfill || !c.HistoryBackfillTime().IsZero() {
+		cursor.lastUpdate = c.HistoryBackfillTime()
+	}
+	historyCursors[key] = cursor
+	return cursor
+}
+
 type historyCollector struct {
 	config        *config.ArrConfig // App configuration
+	cursor        *historyCursor    // How far the history was counted
 	historyMetric *prometheus.Desc  // Total number of history items
+	eventsMetric  *prometheus.Desc  // Total number of history events by type, protocol, indexer and download client
 	errorMetric   *prometheus.Desc  // Error Description for use with InvalidMetric
 }
 
 func NewHistoryCollector(c *config.ArrConfig) *historyCollector {
 	return &historyCollector{
 		config: c,
+		cursor: historyCursorFor(c),
 		historyMetric: prometheus.NewDesc(
 			fmt.Sprintf("%s_history_total", c.App),
 			"Total number of item in the history",
 			nil,
 			prometheus.Labels{"url": c.URL},
 		),
+		eventsMetric: prometheus.NewDesc(
+			fmt.Sprintf("%s_history_events_total", c.App),
+			"Total number of history events by event_type, protocol, indexer and download_client",
+			[]string{"event_type", "protocol", "indexer", "download_client"},
+			prometheus.Labels{"url": c.URL},
+		),
 		errorMetric: prometheus.NewDesc(
 			fmt.Sprintf("%s_history_collector_error", c.App),
 			"Error while collecting metrics",
@@ -36,11 +112,16 @@ func NewHistoryCollector(c *config.ArrConfig) *historyCollector {
 
 func (collector *historyCollector) Describe(ch chan<- *prometheus.Desc) {
 	ch <- collector.historyMetric
+	ch <- collector.eventsMetric
 }
 
 func (collector *historyCollector) Collect(ch chan<- prometheus.Metric) {
+	collector.CollectContext(context.Background(), ch)
+}
+
+func (collector *historyCollector) CollectContext(ctx context.Context, ch chan<- prometheus.Metric) {
 	log := zap.S().With("collector", "history")
-	c, err := client.NewClient(collector.config)
+	c, err := client.SharedClient(collector.config)
 	if err != nil {
 		log.Errorw("Error creating client",
 			"error", err)
@@ -48,11 +129,115 @@ func (collector *historyCollector) Collect(ch chan<- prometheus.Metric) {
 		return
 	}
 	history := model.History{}
-	if err := c.DoRequest("history", &history); err != nil {
+	if err := c.DoRequest(ctx, "history", &history); err != nil {
 		log.Errorw("Error getting history",
 			"error", err)
 		ch <- prometheus.NewInvalidMetric(collector.errorMetric, err)
 		return
 	}
 	ch <- prometheus.MustNewConstMetric(collector.historyMetric, prometheus.GaugeValue, float64(history.TotalRecords))
+
+	cursor := collector.cursor
+	historyCursorsMutex.Lock()
+	cursor.lastPoll = time.Now()
+	historyCursorsMutex.Unlock()
+	cursor.mutex.Lock()
+	defer cursor.mutex.Unlock()
+
+	records := []model.HistoryRecord{}
+	params := client.QueryParams{}
+	params.Add("date", cursor.lastUpdate.In(time.UTC).Format(time.RFC3339))
+	if err := c.DoRequest(ctx, "history/since", &records, params); err != nil {
+		log.Errorw("Error getting history since last update",
+			"since", cursor.lastUpdate,
+			"error", err)
+		ch <- prometheus.NewInvalidMetric(collector.errorMetric, err)
+		return
+	}
+	cursor.count(records)
+
+	for k, count := range cursor.events {
+		ch <- prometheus.MustNewConstMetric(collector.eventsMetric, prometheus.CounterValue, float64(count),
+			k.eventType, k.protocol, k.indexer, k.downloadClient,
+		)
+	}
+}
+
+// count adds the events not counted yet to the counters and moves the cursor
+// to the latest of them. The apps return the events at the date asked for
+// again, so those counted before are skipped by ID.
+func (cursor *historyCursor) count(records []model.HistoryRecord) {
+	sort.SliceStable(records, func(i, j int) bool {
+		return records[i].Date.Before(records[j].Date)
+	})
+	for _, r := range records {
+		if r.Date.Before(cursor.lastUpdate) || cursor.lastIDs[r.ID] && r.Date.Equal(cursor.lastUpdate) {
+			continue
+		}
+		if r.Date.After(cursor.lastUpdate) {
+			cursor.lastUpdate = r.Date
+			cursor.lastIDs = map[int]bool{}
+		}
+		cursor.lastIDs[r.ID] = true
+		cursor.events[historyEventKey{
+			eventType:      historyEventType(r),
+			protocol:       historyProtocol(r.Data["protocol"]),
+			indexer:        r.Data["indexer"],
+			downloadClient: historyDownloadClient(r.Data),
+		}]++
+	}
+}
+
+// historyEventTypes maps the event types of each app to the same names, e.g.
+// Sonarr's episodeFileDeleted and Radarr's movieFileDeleted are both deleted.
+// Lidarr and Readarr record an import per file, like the others, and also one
+// per download, downloadImported, which is left as is.
+var historyEventTypes = map[string]string{
+	"releaseGrabbed":     "grabbed",
+	"trackFileImported":  "downloadFolderImported",
+	"bookFileImported":   "downloadFolderImported",
+	"episodeFileDeleted": "deleted",
+	"movieFileDeleted":   "deleted",
+	"trackFileDeleted":   "deleted",
+	"bookFileDeleted":    "deleted",
+	"episodeFileRenamed": "renamed",
+	"movieFileRenamed":   "renamed",
+	"trackFileRenamed":   "renamed",
+	"bookFileRenamed":    "renamed",
+}
+
+// historyEventType returns the event type of a history event, the same for
+// every app. When an import replaces an existing file, the apps record the
+// replaced file as deleted with the reason Upgrade, which counts as upgrade.
+func historyEventType(r model.HistoryRecord) string {
+	eventType, ok := historyEventTypes[r.EventType]
+	if !ok {
+		return r.EventType
+	}
+	if eventType == "deleted" && strings.EqualFold(r.Data["reason"], "upgrade") {
+		return "upgrade"
+	}
+	return eventType
+}
+
+// historyProtocol names the download protocol of a history event, which the
+// apps record as the number of the protocol.
+func historyProtocol(p string) string {
+	switch p {
+	case "1":
+		return "usenet"
+	case "2":
+		return "torrent"
+	default:
+		return strings.ToLower(p)
+	}
+}
+
+// historyDownloadClient returns the name of the download client of a history
+// event, older versions of the apps only record its type.
+func historyDownloadClient(data map[string]string) string {
+	if name := data["downloadClientName"]; name != "" {
+		return name
+	}
+	return data["downloadClient"]
 }
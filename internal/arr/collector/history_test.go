@@ -6,8 +6,10 @@ import (
 	"os"
 	"strings"
 	"testing"
+	"time"
 
 	"github.com/onedr0p/exportarr/internal/arr/config"
+	"github.com/onedr0p/exportarr/internal/arr/model"
 	"github.com/onedr0p/exportarr/internal/test_util"
 	"github.com/prometheus/client_golang/prometheus/testutil"
 	"github.com/stretchr/testify/require"
@@ -22,32 +24,36 @@ func TestHistoryCollect(t *testing.T) {
 		{
 			name: "radarr",
 			config: &config.ArrConfig{
-				App:        "radarr",
-				ApiVersion: "v3",
+				App:             "radarr",
+				ApiVersion:      "v3",
+				HistoryBackfill: true,
 			},
 			path: "/api/v3/history",
 		},
 		{
 			name: "sonarr",
 			config: &config.ArrConfig{
-				App:        "sonarr",
-				ApiVersion: "v3",
+				App:             "sonarr",
+				ApiVersion:      "v3",
+				HistoryBackfill: true,
 			},
 			path: "/api/v3/history",
 		},
 		{
 			name: "lidarr",
 			config: &config.ArrConfig{
-				App:        "lidarr",
-				ApiVersion: "v1",
+				App:             "lidarr",
+				ApiVersion:      "v1",
+				HistoryBackfill: true,
 			},
 			path: "/api/v1/history",
 		},
 		{
 			name: "readarr",
 			config: &config.ArrConfig{
-				App:        "readarr",
-				ApiVersion: "v1",
+				App:             "readarr",
+				ApiVersion:      "v1",
+				HistoryBackfill: true,
 			},
 			path: "/api/v1/history",
 		},
@@ -105,3 +111,134 @@ func TestHistoryCollect_FailureDoesntPanic(t *testing.T) {
 		require.Error(err)
 	}, "Collecting metrics should not panic on failure")
 }
+
+func TestHistoryCollect_Incremental(t *testing.T) {
+	var tests = []struct {
+		name    string
+		config  *config.ArrConfig
+		date    string // date asked for on the first scrape
+		counted float64
+	}{
+		{
+			name:    "since startup",
+			config:  &config.ArrConfig{},
+			counted: 0,
+		},
+		{
+			name:    "backfill",
+			config:  &config.ArrConfig{HistoryBackfill: true},
+			date:    "0001-01-01T00:00:00Z",
+			counted: 8,
+		},
+		{
+			name:    "backfill since date",
+			config:  &config.ArrConfig{HistoryBackfillSince: "2023-10-11"},
+			date:    "2023-10-11T00:00:00Z",
+			counted: 3,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			require := require.New(t)
+			var dates []string
+			ts, err := test_util.NewTestSharedServer(t, func(w http.ResponseWriter, r *http.Request) {
+				if strings.HasSuffix(r.URL.Path, "/history/since") {
+					dates = append(dates, r.URL.Query().Get("date"))
+				}
+			})
+			require.NoError(err)
+			defer ts.Close()
+
+			tt.config.App = "sonarr"
+			tt.config.ApiVersion = "v3"
+			tt.config.URL = ts.URL
+			tt.config.ApiKey = test_util.API_KEY
+			collector := NewHistoryCollector(tt.config)
+
+			// The app returns the same events on each scrape, they're only counted once
+			for i := 0; i < 2; i++ {
+				testutil.CollectAndCount(collector)
+				total := 0.0
+				for _, count := range collector.cursor.events {
+					total += float64(count)
+				}
+				require.Equal(tt.counted, total)
+			}
+
+			require.Len(dates, 2)
+			if tt.date != "" {
+				require.Equal(tt.date, dates[0])
+				require.Equal("2023-10-12T09:00:00Z", dates[1], "the cursor moves to the latest event")
+			} else {
+				require.Equal(dates[0], dates[1])
+			}
+		})
+	}
+}
+
+func TestHistoryCollect_Reload(t *testing.T) {
+	require := require.New(t)
+	var dates []string
+	ts, err := test_util.NewTestSharedServer(t, func(w http.ResponseWriter, r *http.Request) {
+		if strings.HasSuffix(r.URL.Path, "/history/since") {
+			dates = append(dates, r.URL.Query().Get("date"))
+		}
+	})
+	require.NoError(err)
+	defer ts.Close()
+
+	c := &config.ArrConfig{
+		App:             "sonarr",
+		ApiVersion:      "v3",
+		URL:             ts.URL,
+		ApiKey:          test_util.API_KEY,
+		HistoryBackfill: true,
+	}
+	first := NewHistoryCollector(c)
+	testutil.CollectAndCount(first)
+
+	// Reloads and probes build the collector again, it carries on from the cursor
+	reloaded := *c
+	second := NewHistoryCollector(&reloaded)
+	require.Same(first.cursor, second.cursor)
+	testutil.CollectAndCount(second)
+	require.Equal([]string{"0001-01-01T00:00:00Z", "2023-10-12T09:00:00Z"}, dates)
+	require.Equal(1, second.cursor.events[historyEventKey{eventType: "upgrade"}])
+
+	// Changing the backfill settings starts over
+	reloaded.HistoryBackfillSince = "2023-10-11"
+	require.NotSame(first.cursor, NewHistoryCollector(&reloaded).cursor)
+
+	// Cursors of targets no longer scraped are dropped
+	HISTORY_CURSOR_TTL = 0
+	t.Cleanup(func() { HISTORY_CURSOR_TTL = time.Hour })
+	require.NotSame(first.cursor, NewHistoryCollector(c).cursor)
+}
+
+func TestHistoryEventType(t *testing.T) {
+	var tests = []struct {
+		eventType string
+		reason    string
+		expected  string
+	}{
+		{"grabbed", "", "grabbed"},
+		{"releaseGrabbed", "", "grabbed"},
+		{"downloadFolderImported", "", "downloadFolderImported"},
+		{"trackFileImported", "", "downloadFolderImported"},
+		{"bookFileImported", "", "downloadFolderImported"},
+		{"downloadFailed", "", "downloadFailed"},
+		{"movieFileDeleted", "Manual", "deleted"},
+		{"bookFileDeleted", "MissingFromDisk", "deleted"},
+		{"episodeFileDeleted", "Upgrade", "upgrade"},
+		{"trackFileDeleted", "Upgrade", "upgrade"},
+		{"movieFileRenamed", "", "renamed"},
+		{"downloadIgnored", "", "downloadIgnored"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.eventType+tt.reason, func(t *testing.T) {
+			r := model.HistoryRecord{EventType: tt.eventType, Data: map[string]string{"reason": tt.reason}}
+			require.Equal(t, tt.expected, historyEventType(r))
+		})
+	}
+}
@@ -1,6 +1,7 @@
 package collector
 
 import (
+	"context"
 	"fmt"
 
 	"github.com/onedr0p/exportarr/internal/arr/client"
@@ -127,8 +128,12 @@ func (collector *lidarrCollector) Describe(ch chan<- *prometheus.Desc) {
 }
 
 func (collector *lidarrCollector) Collect(ch chan<- prometheus.Metric) {
+	collector.CollectContext(context.Background(), ch)
+}
+
+func (collector *lidarrCollector) CollectContext(ctx context.Context, ch chan<- prometheus.Metric) {
 	log := zap.S().With("collector", "lidarr")
-	c, err := client.NewClient(collector.config)
+	c, err := client.SharedClient(collector.config)
 	if err != nil {
 		log.Errorf("Error creating client", "error", err)
 		ch <- prometheus.NewInvalidMetric(collector.errorMetric, err)
@@ -147,7 +152,7 @@ func (collector *lidarrCollector) Collect(ch chan<- prometheus.Metric) {
 	)
 
 	artists := model.Artist{}
-	if err := c.DoRequest("artist", &artists); err != nil {
+	if err := c.DoRequest(ctx, "artist", &artists); err != nil {
 		log.Errorw("Error creating client", "error", err)
 		ch <- prometheus.NewInvalidMetric(collector.errorMetric, err)
 		return
@@ -172,7 +177,7 @@ func (collector *lidarrCollector) Collect(ch chan<- prometheus.Metric) {
 			params := client.QueryParams{}
 			params.Add("artistid", fmt.Sprintf("%d", s.Id))
 
-			if err := c.DoRequest("trackfile", &songFile, params); err != nil {
+			if err := c.DoRequest(ctx, "trackfile", &songFile, params); err != nil {
 				log.Errorw("Error getting trackfile", "error", err)
 				ch <- prometheus.NewInvalidMetric(collector.errorMetric, err)
 				return
@@ -184,7 +189,7 @@ func (collector *lidarrCollector) Collect(ch chan<- prometheus.Metric) {
 			}
 
 			album := model.Album{}
-			if err := c.DoRequest("album", &album, params); err != nil {
+			if err := c.DoRequest(ctx, "album", &album, params); err != nil {
 				log.Errorw("Error getting album", "error", err)
 				ch <- prometheus.NewInvalidMetric(collector.errorMetric, err)
 				return
@@ -201,7 +206,7 @@ func (collector *lidarrCollector) Collect(ch chan<- prometheus.Metric) {
 	}
 
 	albumsMissing := model.Missing{}
-	if err := c.DoRequest("wanted/missing", &albumsMissing); err != nil {
+	if err := c.DoRequest(ctx, "wanted/missing", &albumsMissing); err != nil {
 		log.Errorw("Error getting missing albums", "error", err)
 		ch <- prometheus.NewInvalidMetric(collector.errorMetric, err)
 		return
@@ -0,0 +1,174 @@
+package collector
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/onedr0p/exportarr/internal/arr/client"
+	"github.com/onedr0p/exportarr/internal/arr/config"
+	"github.com/onedr0p/exportarr/internal/arr/model"
+	"github.com/prometheus/client_golang/prometheus"
+	"go.uber.org/zap"
+)
+
+// The singular and plural names of the missing items of each app.
+var missingItems = map[string][2]string{
+	"sonarr":  {"episode", "episodes"},
+	"radarr":  {"movie", "movies"},
+	"lidarr":  {"albums", "albums"},
+	"readarr": {"book", "books"},
+}
+
+// The parameters of wanted/missing requests of each app besides the page size.
+var missingParams = map[string]map[string]string{
+	"sonarr": {"sortKey": "airDateUtc"},
+}
+
+type missingCollector struct {
+	config            *config.ArrConfig // App configuration
+	missingAgeMetrics missingAgeDescs   // Time since missing items were released
+	errorMetric       *prometheus.Desc  // Error Description for use with InvalidMetric
+}
+
+func NewMissingCollector(c *config.ArrConfig) *missingCollector {
+	items := missingItems[c.App]
+	return &missingCollector{
+		config:            c,
+		missingAgeMetrics: newMissingAgeDescs(c.App, items[0], items[1], c.URL),
+		errorMetric: prometheus.NewDesc(
+			fmt.Sprintf("%s_missing_collector_error", c.App),
+			"Error while collecting metrics",
+			nil,
+			prometheus.Labels{"url": c.URL},
+		),
+	}
+}
+
+func (collector *missingCollector) Describe(ch chan<- *prometheus.Desc) {
+	collector.missingAgeMetrics.describe(ch)
+}
+
+func (collector *missingCollector) Collect(ch chan<- prometheus.Metric) {
+	collector.CollectContext(context.Background(), ch)
+}
+
+func (collector *missingCollector) CollectContext(ctx context.Context, ch chan<- prometheus.Metric) {
+	log := zap.S().With("collector", "missing")
+	if !collector.config.Supports(config.FeatureWantedMissing) {
+		log.Debugw("Skipping missing items, the app doesn't support them",
+			"app", collector.config.App)
+		return
+	}
+	c, err := client.SharedClient(collector.config)
+	if err != nil {
+		log.Errorw("Error creating client",
+			"error", err)
+		ch <- prometheus.NewInvalidMetric(collector.errorMetric, err)
+		return
+	}
+
+	params := client.QueryParams{}
+	params.Add("pageSize", "250")
+	for k, v := range missingParams[collector.config.App] {
+		params.Add(k, v)
+	}
+	missing, err := getAllPages[model.MissingItem](ctx, c, "wanted/missing", params)
+	if err != nil {
+		log.Errorw("Error getting missing items",
+			"error", err)
+		ch <- prometheus.NewInvalidMetric(collector.errorMetric, err)
+		return
+	}
+
+	missingAges := newMissingAges()
+	for _, r := range missing.Records {
+		missingAges.observe(r.AirDateUtc, r.DigitalRelease, r.PhysicalRelease, r.ReleaseDate)
+	}
+	missingAges.collect(collector.missingAgeMetrics, ch)
+}
+
+// timeNow is replaced in tests to make the ages of missing items predictable.
+var timeNow = time.Now
+
+// missingAgeBuckets are the upper bounds of the missing age histograms in
+// seconds: a day, a week, 30 days, 90 days, 180 days, a year, 2 and 5 years.
+var missingAgeBuckets = []float64{86400, 604800, 2592000, 7776000, 15552000, 31536000, 63072000, 157680000}
+
+// missingAgeDescs describes how long the missing items of an app, e.g. the
+// episodes of Sonarr, have been released. Metrics are named after the item
+// like the app's other metrics, e.g. sonarr_episode_missing_age_seconds.
+type missingAgeDescs struct {
+	age        *prometheus.Desc
+	unreleased *prometheus.Desc
+}
+
+func newMissingAgeDescs(app string, item string, items string, url string) missingAgeDescs {
+	return missingAgeDescs{
+		age: prometheus.NewDesc(
+			fmt.Sprintf("%s_%s_missing_age_seconds", app, item),
+			fmt.Sprintf("Time since missing %s were released", items),
+			nil,
+			prometheus.Labels{"url": url},
+		),
+		unreleased: prometheus.NewDesc(
+			fmt.Sprintf("%s_%s_missing_unreleased_total", app, item),
+			fmt.Sprintf("Total number of missing %s not released yet or without a release date", items),
+			nil,
+			prometheus.Labels{"url": url},
+		),
+	}
+}
+
+func (d missingAgeDescs) describe(ch chan<- *prometheus.Desc) {
+	ch <- d.age
+	ch <- d.unreleased
+}
+
+// missingAges accumulates the ages of missing items for a missingAgeDescs.
+type missingAges struct {
+	now        time.Time
+	count      uint64
+	sum        float64
+	buckets    map[float64]uint64
+	unreleased int
+}
+
+func newMissingAges() *missingAges {
+	ret := &missingAges{
+		now:     timeNow(),
+		buckets: make(map[float64]uint64, len(missingAgeBuckets)),
+	}
+	for _, b := range missingAgeBuckets {
+		ret.buckets[b] = 0
+	}
+	return ret
+}
+
+// observe adds a missing item released at the earliest of the given release
+// dates. Zero dates are unknown, and an item without any is unreleased.
+func (m *missingAges) observe(released ...time.Time) {
+	var first time.Time
+	for _, t := range released {
+		if !t.IsZero() && (first.IsZero() || t.Before(first)) {
+			first = t
+		}
+	}
+	if first.IsZero() || first.After(m.now) {
+		m.unreleased++
+		return
+	}
+	age := m.now.Sub(first).Seconds()
+	m.count++
+	m.sum += age
+	for _, b := range missingAgeBuckets {
+		if age <= b {
+			m.buckets[b]++
+		}
+	}
+}
+
+func (m *missingAges) collect(d missingAgeDescs, ch chan<- prometheus.Metric) {
+	ch <- prometheus.MustNewConstHistogram(d.age, m.count, m.sum, m.buckets)
+	ch <- prometheus.MustNewConstMetric(d.unreleased, prometheus.GaugeValue, float64(m.unreleased))
+}
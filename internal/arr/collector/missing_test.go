@@ -0,0 +1,151 @@
+package collector
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/onedr0p/exportarr/internal/arr/config"
+	"github.com/onedr0p/exportarr/internal/test_util"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/stretchr/testify/require"
+)
+
+// setTimeNow fixes the time the ages of missing items are measured at.
+func setTimeNow(t *testing.T, now time.Time) {
+	prev := timeNow
+	timeNow = func() time.Time { return now }
+	t.Cleanup(func() { timeNow = prev })
+}
+
+var testNow = time.Date(2023, 10, 15, 0, 0, 0, 0, time.UTC)
+
+func TestMissingAges(t *testing.T) {
+	require := require.New(t)
+	setTimeNow(t, testNow)
+
+	m := newMissingAges()
+	m.observe(testNow.Add(-12 * time.Hour))
+	m.observe(time.Time{}, testNow.AddDate(-1, 0, 0), testNow.AddDate(0, -1, 0))
+	m.observe(testNow.AddDate(0, 0, 1))
+	m.observe()
+
+	require.Equal(uint64(2), m.count)
+	require.Equal(2, m.unreleased)
+	require.Equal((12*time.Hour + 365*24*time.Hour).Seconds(), m.sum, "the earliest release date counts")
+	require.Equal(uint64(1), m.buckets[86400])
+	require.Equal(uint64(1), m.buckets[15552000])
+	require.Equal(uint64(2), m.buckets[31536000])
+	require.Equal(uint64(2), m.buckets[157680000])
+}
+
+func TestMissingCollect(t *testing.T) {
+	var tests = []struct {
+		name          string
+		config        *config.ArrConfig
+		fixtures_path string
+		params        map[string]string
+	}{
+		{
+			name: "sonarr",
+			config: &config.ArrConfig{
+				App:        "sonarr",
+				ApiVersion: "v3",
+			},
+			fixtures_path: sonarr_test_fixtures_path,
+			params:        map[string]string{"sortKey": "airDateUtc"},
+		},
+		{
+			name: "radarr",
+			config: withAPI(&config.ArrConfig{
+				App:        "radarr",
+				ApiVersion: "v3",
+			}, "5.2.6.8376"),
+			fixtures_path: radarr_test_fixtures_path,
+		},
+		{
+			name: "readarr",
+			config: &config.ArrConfig{
+				App:        "readarr",
+				ApiVersion: "v1",
+			},
+			fixtures_path: readarr_test_fixtures_path,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			require := require.New(t)
+			setTimeNow(t, testNow)
+			ts, err := test_util.NewTestServer(t, tt.fixtures_path, func(w http.ResponseWriter, r *http.Request) {
+				require.True(strings.HasSuffix(r.URL.Path, "/wanted/missing"), r.URL.Path)
+				require.Equal("250", r.URL.Query().Get("pageSize"))
+				for k, v := range tt.params {
+					require.Equal(v, r.URL.Query().Get(k))
+				}
+			})
+			require.NoError(err)
+
+			defer ts.Close()
+
+			tt.config.URL = ts.URL
+			tt.config.ApiKey = test_util.API_KEY
+
+			collector := NewMissingCollector(tt.config)
+
+			b, err := os.ReadFile(tt.fixtures_path + "expected_missing_metrics.txt")
+			require.NoError(err)
+
+			expected := strings.Replace(string(b), "SOMEURL", ts.URL, -1)
+			f := strings.NewReader(expected)
+
+			require.NotPanics(func() {
+				err = testutil.CollectAndCompare(collector, f)
+			})
+			require.NoError(err)
+		})
+	}
+}
+
+func TestMissingCollect_Unsupported(t *testing.T) {
+	require := require.New(t)
+
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		require.Fail("no request should be made", r.URL.Path)
+	}))
+	defer ts.Close()
+
+	config := withAPI(&config.ArrConfig{
+		App:        "radarr",
+		ApiVersion: "v3",
+		URL:        ts.URL,
+		ApiKey:     test_util.API_KEY,
+	}, "4.7.5.7809")
+	require.Zero(testutil.CollectAndCount(NewMissingCollector(config)))
+}
+
+func TestMissingCollect_FailureDoesntPanic(t *testing.T) {
+	require := require.New(t)
+
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusInternalServerError)
+	}))
+	defer ts.Close()
+
+	config := &config.ArrConfig{
+		App:    "sonarr",
+		URL:    ts.URL,
+		ApiKey: test_util.API_KEY,
+	}
+	collector := NewMissingCollector(config)
+
+	f := strings.NewReader("")
+
+	require.NotPanics(func() {
+		err := testutil.CollectAndCompare(collector, f)
+		require.Error(err)
+	}, "Collecting metrics should not panic on failure")
+}
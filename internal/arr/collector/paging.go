@@ -0,0 +1,35 @@
+package collector
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/onedr0p/exportarr/internal/arr/client"
+	"github.com/onedr0p/exportarr/internal/arr/model"
+)
+
+// getAllPages requests every page of a paged endpoint like queue or
+// wanted/cutoff with params and returns the first page with the records of all
+// of them.
+func getAllPages[T any](ctx context.Context, c *client.Client, endpoint string, params client.QueryParams) (*model.Page[T], error) {
+	params.Set("page", "1")
+	first := model.Page[T]{}
+	if err := c.DoRequest(ctx, endpoint, &first, params); err != nil {
+		return nil, err
+	}
+	// Calculate total pages
+	totalPages := 1
+	if first.PageSize > 0 {
+		totalPages = (first.TotalRecords + first.PageSize - 1) / first.PageSize
+	}
+	// Paginate
+	for p := 2; p <= totalPages; p++ {
+		params.Set("page", fmt.Sprintf("%d", p))
+		page := model.Page[T]{}
+		if err := c.DoRequest(ctx, endpoint, &page, params); err != nil {
+			return nil, fmt.Errorf("page %d: %w", p, err)
+		}
+		first.Records = append(first.Records, page.Records...)
+	}
+	return &first, nil
+}
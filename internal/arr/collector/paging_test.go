@@ -0,0 +1,46 @@
+package collector
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/onedr0p/exportarr/internal/arr/client"
+	"github.com/onedr0p/exportarr/internal/arr/config"
+	"github.com/onedr0p/exportarr/internal/test_util"
+	"github.com/stretchr/testify/require"
+)
+
+func TestGetAllPages(t *testing.T) {
+	require := require.New(t)
+
+	var pages []string
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		page := r.URL.Query().Get("page")
+		pages = append(pages, page)
+		require.Equal("2", r.URL.Query().Get("pageSize"))
+		switch page {
+		case "1":
+			fmt.Fprint(w, `{"page":1,"pageSize":2,"totalRecords":3,"records":[{"id":1},{"id":2}]}`)
+		case "2":
+			fmt.Fprint(w, `{"page":2,"pageSize":2,"totalRecords":3,"records":[{"id":3}]}`)
+		}
+	}))
+	defer ts.Close()
+
+	c, err := client.NewClient(&config.ArrConfig{App: "sonarr", ApiVersion: "v3", URL: ts.URL, ApiKey: test_util.API_KEY})
+	require.NoError(err)
+
+	params := client.QueryParams{}
+	params.Add("pageSize", "2")
+	all, err := getAllPages[struct {
+		ID int `json:"id"`
+	}](context.Background(), c, "wanted/cutoff", params)
+	require.NoError(err)
+	require.Equal([]string{"1", "2"}, pages)
+	require.Equal(3, all.TotalRecords)
+	require.Len(all.Records, 3)
+	require.Equal(3, all.Records[2].ID)
+}
@@ -1,6 +1,7 @@
 package collector
 
 import (
+	"context"
 	"strings"
 	"sync"
 	"time"
@@ -278,9 +279,13 @@ func (collector *prowlarrCollector) Describe(ch chan<- *prometheus.Desc) {
 }
 
 func (collector *prowlarrCollector) Collect(ch chan<- prometheus.Metric) {
+	collector.CollectContext(context.Background(), ch)
+}
+
+func (collector *prowlarrCollector) CollectContext(ctx context.Context, ch chan<- prometheus.Metric) {
 	total := time.Now()
 	log := zap.S().With("collector", "prowlarr")
-	c, err := client.NewClient(collector.config)
+	c, err := client.SharedClient(collector.config)
 	if err != nil {
 		log.Errorf("Error creating client: %s", err)
 		ch <- prometheus.NewInvalidMetric(collector.errorMetric, err)
@@ -290,7 +295,7 @@ func (collector *prowlarrCollector) Collect(ch chan<- prometheus.Metric) {
 	var enabledIndexers = 0
 
 	indexers := model.Indexer{}
-	if err := c.DoRequest("indexer", &indexers); err != nil {
+	if err := c.DoRequest(ctx, "indexer", &indexers); err != nil {
 		log.Errorf("Error getting indexers: %s", err)
 		ch <- prometheus.NewInvalidMetric(collector.errorMetric, err)
 		return
@@ -322,7 +327,7 @@ func (collector *prowlarrCollector) Collect(ch chan<- prometheus.Metric) {
 	params.Add("startDate", startDate.Format(time.RFC3339))
 	params.Add("endDate", endDate.Format(time.RFC3339))
 
-	if err := c.DoRequest("indexerstats", &stats, params); err != nil {
+	if err := c.DoRequest(ctx, "indexerstats", &stats, params); err != nil {
 		log.Errorf("Error getting indexer stats: %s", err)
 		ch <- prometheus.NewInvalidMetric(collector.errorMetric, err)
 		return
@@ -1,6 +1,7 @@
 package collector
 
 import (
+	"context"
 	"fmt"
 
 	"github.com/onedr0p/exportarr/internal/arr/client"
@@ -10,6 +11,14 @@ import (
 	"go.uber.org/zap"
 )
 
+// The parameter including unknown items in the queue of each app.
+var unknownQueueItemsParams = map[string]string{
+	"radarr":  "includeUnknownMovieItems",
+	"sonarr":  "includeUnknownSeriesItems",
+	"lidarr":  "includeUnknownArtistItems",
+	"readarr": "includeUnknownAuthorItems",
+}
+
 type queueCollector struct {
 	config      *config.ArrConfig // App configuration
 	queueMetric *prometheus.Desc  // Total number of queue items
@@ -39,8 +48,12 @@ func (collector *queueCollector) Describe(ch chan<- *prometheus.Desc) {
 }
 
 func (collector *queueCollector) Collect(ch chan<- prometheus.Metric) {
+	collector.CollectContext(context.Background(), ch)
+}
+
+func (collector *queueCollector) CollectContext(ctx context.Context, ch chan<- prometheus.Metric) {
 	log := zap.S().With("collector", "queue")
-	c, err := client.NewClient(collector.config)
+	c, err := client.SharedClient(collector.config)
 	if err != nil {
 		log.Errorw("Error creating client",
 			"error", err)
@@ -49,40 +62,18 @@ func (collector *queueCollector) Collect(ch chan<- prometheus.Metric) {
 	}
 
 	params := client.QueryParams{}
-	params.Add("page", "1")
-	if collector.config.EnableUnknownQueueItems {
-		if collector.config.App == "sonarr" {
-			params.Add("includeUnknownSeriesItems", "true")
-		} else if collector.config.App == "radarr" {
-			params.Add("includeUnknownMovieItems", "true")
-		}
+	if p, ok := unknownQueueItemsParams[collector.config.App]; ok && collector.config.EnableUnknownQueueItems {
+		params.Add(p, "true")
 	}
 
-	queue := model.Queue{}
-	if err := c.DoRequest("queue", &queue, params); err != nil {
+	queue, err := getAllPages[model.QueueRecords](ctx, c, "queue", params)
+	if err != nil {
 		log.Errorw("Error getting queue",
 			"error", err)
 		ch <- prometheus.NewInvalidMetric(collector.errorMetric, err)
 		return
 	}
-	// Calculate total pages
-	var totalPages = (queue.TotalRecords + queue.PageSize - 1) / queue.PageSize
-	// Paginate
-	var queueStatusAll = make([]model.QueueRecords, 0, queue.TotalRecords)
-	queueStatusAll = append(queueStatusAll, queue.Records...)
-	if totalPages > 1 {
-		for page := 2; page <= totalPages; page++ {
-			params.Set("page", fmt.Sprintf("%d", page))
-			if err := c.DoRequest("queue", &queue, params); err != nil {
-				log.Errorw("Error getting queue page",
-					"page", page,
-					"error", err)
-				ch <- prometheus.NewInvalidMetric(collector.errorMetric, err)
-				return
-			}
-			queueStatusAll = append(queueStatusAll, queue.Records...)
-		}
-	}
+	queueStatusAll := queue.Records
 	// Group metrics by status, download_status and download_state
 	if len(queueStatusAll) > 0 {
 		var queueMetrics prometheus.Metric
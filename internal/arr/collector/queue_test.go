@@ -105,3 +105,48 @@ func TestQueueCollect_FailureDoesntPanic(t *testing.T) {
 		require.Error(err)
 	}, "Collecting metrics should not panic on failure")
 }
+
+func TestQueueCollect_UnknownItems(t *testing.T) {
+	var tests = []struct {
+		name    string
+		config  *config.ArrConfig
+		param   string
+		present bool
+	}{
+		{
+			name: "lidarr",
+			config: &config.ArrConfig{
+				App:        "lidarr",
+				ApiVersion: "v1",
+			},
+			param:   "includeUnknownArtistItems",
+			present: true,
+		},
+		{
+			name: "radarr",
+			config: &config.ArrConfig{
+				App:        "radarr",
+				ApiVersion: "v3",
+			},
+			param:   "includeUnknownMovieItems",
+			present: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			require := require.New(t)
+			ts, err := test_util.NewTestSharedServer(t, func(w http.ResponseWriter, r *http.Request) {
+				require.Equal(tt.present, r.URL.Query().Has(tt.param))
+			})
+			require.NoError(err)
+			defer ts.Close()
+
+			tt.config.URL = ts.URL
+			tt.config.ApiKey = test_util.API_KEY
+			tt.config.EnableUnknownQueueItems = true
+
+			require.NotZero(testutil.CollectAndCount(NewQueueCollector(tt.config)))
+		})
+	}
+}
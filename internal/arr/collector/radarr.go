@@ -1,6 +1,7 @@
 package collector
 
 import (
+	"context"
 	"github.com/onedr0p/exportarr/internal/arr/client"
 	"github.com/onedr0p/exportarr/internal/arr/config"
 	"github.com/onedr0p/exportarr/internal/arr/model"
@@ -109,8 +110,12 @@ func (collector *radarrCollector) Describe(ch chan<- *prometheus.Desc) {
 }
 
 func (collector *radarrCollector) Collect(ch chan<- prometheus.Metric) {
+	collector.CollectContext(context.Background(), ch)
+}
+
+func (collector *radarrCollector) CollectContext(ctx context.Context, ch chan<- prometheus.Metric) {
 	log := zap.S().With("collector", "radarr")
-	c, err := client.NewClient(collector.config)
+	c, err := client.SharedClient(collector.config)
 	if err != nil {
 		log.Errorw("Error creating client", "error", err)
 		ch <- prometheus.NewInvalidMetric(collector.errorMetric, err)
@@ -136,7 +141,7 @@ func (collector *radarrCollector) Collect(ch chan<- prometheus.Metric) {
 	params.Add("excludeLocalCovers", "true")
 
 	// https://radarr.video/docs/api/#/Movie/get_api_v3_movie
-	if err := c.DoRequest("movie", &movies, params); err != nil {
+	if err := c.DoRequest(ctx, "movie", &movies, params); err != nil {
 		log.Errorw("Error getting movies", "error", err)
 		ch <- prometheus.NewInvalidMetric(collector.errorMetric, err)
 		return
@@ -170,7 +175,7 @@ func (collector *radarrCollector) Collect(ch chan<- prometheus.Metric) {
 
 	tagObjects := model.TagMovies{}
 	// https://radarr.video/docs/api/#/TagDetails/get_api_v3_tag_detail
-	if err := c.DoRequest("tag/detail", &tagObjects); err != nil {
+	if err := c.DoRequest(ctx, "tag/detail", &tagObjects); err != nil {
 		log.Errorw("Error getting Tags", "error", err)
 		ch <- prometheus.NewInvalidMetric(collector.errorMetric, err)
 		return
@@ -1,6 +1,7 @@
 package collector
 
 import (
+	"context"
 	"time"
 
 	"github.com/onedr0p/exportarr/internal/arr/client"
@@ -119,9 +120,13 @@ func (c *readarrCollector) Describe(ch chan<- *prometheus.Desc) {
 }
 
 func (collector *readarrCollector) Collect(ch chan<- prometheus.Metric) {
+	collector.CollectContext(context.Background(), ch)
+}
+
+func (collector *readarrCollector) CollectContext(ctx context.Context, ch chan<- prometheus.Metric) {
 	total := time.Now()
 	log := zap.S().With("collector", "readarr")
-	c, err := client.NewClient(collector.config)
+	c, err := client.SharedClient(collector.config)
 	if err != nil {
 		log.Errorw("Error creating client", "error", err)
 		ch <- prometheus.NewInvalidMetric(collector.errorMetric, err)
@@ -142,7 +147,7 @@ func (collector *readarrCollector) Collect(ch chan<- prometheus.Metric) {
 	)
 
 	authors := model.Author{}
-	if err := c.DoRequest("author", &authors); err != nil {
+	if err := c.DoRequest(ctx, "author", &authors); err != nil {
 		log.Errorw("Error getting authors", "error", err)
 		ch <- prometheus.NewInvalidMetric(collector.errorMetric, err)
 		return
@@ -172,7 +177,7 @@ func (collector *readarrCollector) Collect(ch chan<- prometheus.Metric) {
 	}
 
 	books := model.Book{}
-	if err := c.DoRequest("book", &books); err != nil {
+	if err := c.DoRequest(ctx, "book", &books); err != nil {
 		log.Errorw("Error getting books",
 			"error", err)
 		ch <- prometheus.NewInvalidMetric(collector.errorMetric, err)
@@ -1,6 +1,7 @@
 package collector
 
 import (
+	"context"
 	"fmt"
 
 	"github.com/onedr0p/exportarr/internal/arr/client"
@@ -39,8 +40,12 @@ func (collector *rootFolderCollector) Describe(ch chan<- *prometheus.Desc) {
 }
 
 func (collector *rootFolderCollector) Collect(ch chan<- prometheus.Metric) {
+	collector.CollectContext(context.Background(), ch)
+}
+
+func (collector *rootFolderCollector) CollectContext(ctx context.Context, ch chan<- prometheus.Metric) {
 	log := zap.S().With("collector", "rootfolder")
-	c, err := client.NewClient(collector.config)
+	c, err := client.SharedClient(collector.config)
 	if err != nil {
 		log.Errorw("Error creating client",
 			"error", err)
@@ -48,7 +53,7 @@ func (collector *rootFolderCollector) Collect(ch chan<- prometheus.Metric) {
 		return
 	}
 	rootFolders := model.RootFolder{}
-	if err := c.DoRequest("rootfolder", &rootFolders); err != nil {
+	if err := c.DoRequest(ctx, "rootfolder", &rootFolders); err != nil {
 		log.Errorw("Error getting rootfolder",
 			"error", err)
 		ch <- prometheus.NewInvalidMetric(collector.errorMetric, err)
@@ -1,6 +1,7 @@
 package collector
 
 import (
+	"context"
 	"fmt"
 	"time"
 
@@ -152,9 +153,13 @@ func (collector *sonarrCollector) Describe(ch chan<- *prometheus.Desc) {
 }
 
 func (collector *sonarrCollector) Collect(ch chan<- prometheus.Metric) {
+	collector.CollectContext(context.Background(), ch)
+}
+
+func (collector *sonarrCollector) CollectContext(ctx context.Context, ch chan<- prometheus.Metric) {
 	total := time.Now()
 	log := zap.S().With("collector", "sonarr")
-	c, err := client.NewClient(collector.config)
+	c, err := client.SharedClient(collector.config)
 	if err != nil {
 		log.Errorw("Error creating client",
 			"error", err)
@@ -179,7 +184,7 @@ func (collector *sonarrCollector) Collect(ch chan<- prometheus.Metric) {
 
 	cseries := []time.Duration{}
 	series := model.Series{}
-	if err := c.DoRequest("series", &series); err != nil {
+	if err := c.DoRequest(ctx, "series", &series); err != nil {
 		log.Errorw("Error getting series",
 			"error", err)
 		ch <- prometheus.NewInvalidMetric(collector.errorMetric, err)
@@ -223,7 +228,7 @@ func (collector *sonarrCollector) Collect(ch chan<- prometheus.Metric) {
 			params := client.QueryParams{}
 			params.Add("seriesId", fmt.Sprintf("%d", s.Id))
 
-			if err := c.DoRequest("episodefile", &episodeFile, params); err != nil {
+			if err := c.DoRequest(ctx, "episodefile", &episodeFile, params); err != nil {
 				log.Errorw("Error getting episodefile",
 					"error", err)
 				ch <- prometheus.NewInvalidMetric(collector.errorMetric, err)
@@ -236,7 +241,7 @@ func (collector *sonarrCollector) Collect(ch chan<- prometheus.Metric) {
 			}
 
 			episode := model.Episode{}
-			if err := c.DoRequest("episode", &episode, params); err != nil {
+			if err := c.DoRequest(ctx, "episode", &episode, params); err != nil {
 				log.Errorw("Error getting episode",
 					"error", err)
 				ch <- prometheus.NewInvalidMetric(collector.errorMetric, err)
@@ -264,7 +269,7 @@ func (collector *sonarrCollector) Collect(ch chan<- prometheus.Metric) {
 	params := client.QueryParams{}
 	params.Add("sortKey", "airDateUtc")
 
-	if err := c.DoRequest("wanted/missing", &episodesMissing, params); err != nil {
+	if err := c.DoRequest(ctx, "wanted/missing", &episodesMissing, params); err != nil {
 		log.Errorw("Error getting missing",
 			"error", err)
 		ch <- prometheus.NewInvalidMetric(collector.errorMetric, err)
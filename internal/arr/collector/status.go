@@ -1,7 +1,9 @@
 package collector
 
 import (
+	"context"
 	"fmt"
+	"strconv"
 
 	"github.com/onedr0p/exportarr/internal/arr/client"
 	"github.com/onedr0p/exportarr/internal/arr/config"
@@ -13,6 +15,11 @@ import (
 type systemStatusCollector struct {
 	config       *config.ArrConfig // App configuration
 	systemStatus *prometheus.Desc  // Total number of system statuses
+	systemInfo   *prometheus.Desc  // Version, runtime and environment of the app
+	startTime    *prometheus.Desc  // When the app was started
+	migration    *prometheus.Desc  // Database migration version of the app
+	apiInfo      *prometheus.Desc  // API version used and app version detected
+	capability   *prometheus.Desc  // Features of the app version detected
 	errorMetric  *prometheus.Desc  // Error Description for use with InvalidMetric
 }
 
@@ -25,6 +32,37 @@ func NewSystemStatusCollector(c *config.ArrConfig) *systemStatusCollector {
 			nil,
 			prometheus.Labels{"url": c.URL},
 		),
+		systemInfo: prometheus.NewDesc(
+			fmt.Sprintf("%s_system_info", c.App),
+			"Version, runtime and environment of the app",
+			[]string{"version", "branch", "instance_name", "runtime_name", "runtime_version", "os_name", "os_version",
+				"database_type", "database_version", "is_docker", "authentication"},
+			prometheus.Labels{"url": c.URL},
+		),
+		startTime: prometheus.NewDesc(
+			fmt.Sprintf("%s_start_time_seconds", c.App),
+			"Start time of the app since unix epoch in seconds",
+			nil,
+			prometheus.Labels{"url": c.URL},
+		),
+		migration: prometheus.NewDesc(
+			fmt.Sprintf("%s_migration_version", c.App),
+			"Version of the last database migration applied by the app",
+			nil,
+			prometheus.Labels{"url": c.URL},
+		),
+		apiInfo: prometheus.NewDesc(
+			fmt.Sprintf("%s_api_info", c.App),
+			"API version used and app version detected, empty until it was detected",
+			[]string{"api_version", "version"},
+			prometheus.Labels{"url": c.URL},
+		),
+		capability: prometheus.NewDesc(
+			fmt.Sprintf("%s_api_capability", c.App),
+			"Whether the app version detected supports the capability, assumed until it was detected",
+			[]string{"capability"},
+			prometheus.Labels{"url": c.URL},
+		),
 		errorMetric: prometheus.NewDesc(
 			fmt.Sprintf("%s_status_collector_error", c.App),
 			"Error while collecting metrics",
@@ -36,11 +74,33 @@ func NewSystemStatusCollector(c *config.ArrConfig) *systemStatusCollector {
 
 func (collector *systemStatusCollector) Describe(ch chan<- *prometheus.Desc) {
 	ch <- collector.systemStatus
+	ch <- collector.systemInfo
+	ch <- collector.startTime
+	ch <- collector.migration
+	ch <- collector.apiInfo
+	ch <- collector.capability
 }
 
 func (collector *systemStatusCollector) Collect(ch chan<- prometheus.Metric) {
+	collector.CollectContext(context.Background(), ch)
+}
+
+func (collector *systemStatusCollector) CollectContext(ctx context.Context, ch chan<- prometheus.Metric) {
 	log := zap.S().With("collector", "system_status")
-	c, err := client.NewClient(collector.config)
+	version := ""
+	if api := collector.config.API(); api != nil {
+		version = api.Version
+	}
+	ch <- prometheus.MustNewConstMetric(collector.apiInfo, prometheus.GaugeValue, 1, collector.config.ApiVersion, version)
+	for _, f := range config.Features(collector.config.App) {
+		supported := 0.0
+		if collector.config.Supports(f) {
+			supported = 1
+		}
+		ch <- prometheus.MustNewConstMetric(collector.capability, prometheus.GaugeValue, supported, string(f))
+	}
+
+	c, err := client.SharedClient(collector.config)
 	if err != nil {
 		log.Errorw("Error creating client",
 			"error", err)
@@ -48,11 +108,33 @@ func (collector *systemStatusCollector) Collect(ch chan<- prometheus.Metric) {
 		return
 	}
 	systemStatus := model.SystemStatus{}
-	if err := c.DoRequest("system/status", &systemStatus); err != nil {
+	if err := c.DoRequest(ctx, "system/status", &systemStatus); err != nil {
+		log.Errorw("Error getting system status",
+			"error", err)
 		ch <- prometheus.MustNewConstMetric(collector.systemStatus, prometheus.GaugeValue, float64(0.0))
+		ch <- prometheus.NewInvalidMetric(collector.errorMetric, err)
 	} else if (model.SystemStatus{}) == systemStatus {
 		ch <- prometheus.MustNewConstMetric(collector.systemStatus, prometheus.GaugeValue, float64(0.0))
 	} else {
 		ch <- prometheus.MustNewConstMetric(collector.systemStatus, prometheus.GaugeValue, float64(1.0))
+		ch <- prometheus.MustNewConstMetric(collector.systemInfo, prometheus.GaugeValue, float64(1.0),
+			systemStatus.Version,
+			systemStatus.Branch,
+			systemStatus.InstanceName,
+			systemStatus.RuntimeName,
+			systemStatus.RuntimeVersion,
+			systemStatus.OsName,
+			systemStatus.OsVersion,
+			systemStatus.DatabaseType,
+			systemStatus.DatabaseVersion,
+			strconv.FormatBool(systemStatus.IsDocker),
+			systemStatus.Authentication,
+		)
+		if !systemStatus.StartTime.IsZero() {
+			ch <- prometheus.MustNewConstMetric(collector.startTime, prometheus.GaugeValue, float64(systemStatus.StartTime.Unix()))
+		}
+		if systemStatus.MigrationVersion > 0 {
+			ch <- prometheus.MustNewConstMetric(collector.migration, prometheus.GaugeValue, float64(systemStatus.MigrationVersion))
+		}
 	}
 }
@@ -13,6 +13,12 @@ import (
 	"github.com/stretchr/testify/require"
 )
 
+// withAPI sets the version detected of the app of c.
+func withAPI(c *config.ArrConfig, version string) *config.ArrConfig {
+	c.SetAPI(&config.APIInfo{App: c.App, Version: version})
+	return c
+}
+
 func TestStatusCollect(t *testing.T) {
 	var tests = []struct {
 		name   string
@@ -73,6 +79,7 @@ func TestStatusCollect(t *testing.T) {
 
 			expected := strings.Replace(string(b), "SOMEURL", ts.URL, -1)
 			expected = strings.Replace(expected, "APP", tt.config.App, -1)
+			expected = strings.Replace(expected, "APIVERSION", tt.config.ApiVersion, -1)
 
 			f := strings.NewReader(expected)
 
@@ -0,0 +1,107 @@
+package config
+
+import (
+	"strconv"
+	"strings"
+	"sync/atomic"
+
+	"golang.org/x/exp/slices"
+)
+
+// ApiVersions is the API version exportarr reads of each app. Each app has a
+// single one, the newer API versions of the apps aren't supported yet.
+var ApiVersions = map[string]string{
+	"radarr":   "v3",
+	"sonarr":   "v3",
+	"lidarr":   "v1",
+	"readarr":  "v1",
+	"prowlarr": "v1",
+}
+
+// APIInfo is what was detected about an app from its system/status endpoint.
+type APIInfo struct {
+	App     string // The app serving the URL, lowercased, e.g. sonarr
+	Version string // The app's version, e.g. 4.0.1.929
+}
+
+// API returns what was detected about the app, nil until it was detected.
+func (c *ArrConfig) API() *APIInfo {
+	if c.api == nil {
+		return nil
+	}
+	return c.api.Load()
+}
+
+// SetAPI sets what was detected about the app. Collectors may read it
+// concurrently once the config was loaded by LoadArrConfig or from a targets
+// file; configs built otherwise must be set before they are shared.
+func (c *ArrConfig) SetAPI(api *APIInfo) {
+	if c.api == nil {
+		c.api = &atomic.Pointer[APIInfo]{}
+	}
+	c.api.Store(api)
+}
+
+// Feature is something only some versions of an app support.
+type Feature string
+
+const (
+	// The wanted/cutoff endpoint lists items below their quality profile's cutoff.
+	FeatureWantedCutoff Feature = "wanted-cutoff"
+	// The wanted/missing endpoint lists monitored items without a file.
+	FeatureWantedMissing Feature = "wanted-missing"
+)
+
+// features maps each feature to the first version of each app supporting it.
+var features = map[Feature]map[string]string{
+	FeatureWantedCutoff:  {"radarr": "5.0", "sonarr": "3.0", "lidarr": "1.0", "readarr": "0.1"},
+	FeatureWantedMissing: {"radarr": "5.0", "sonarr": "3.0", "lidarr": "1.0", "readarr": "0.1"},
+}
+
+// Features returns the features known for app, sorted.
+func Features(app string) []Feature {
+	ret := []Feature{}
+	for f, versions := range features {
+		if _, ok := versions[app]; ok {
+			ret = append(ret, f)
+		}
+	}
+	slices.Sort(ret)
+	return ret
+}
+
+// Supports reports whether the app supports f. Until the app's version was
+// detected, any feature known for the app is assumed to be supported.
+func (c *ArrConfig) Supports(f Feature) bool {
+	min, ok := features[f][c.App]
+	if !ok {
+		return false
+	}
+	api := c.API()
+	if api == nil || api.Version == "" {
+		return true
+	}
+	return CompareVersions(api.Version, min) >= 0
+}
+
+// CompareVersions compares dotted versions like 4.0.1.929 part by part,
+// returning -1, 0 or 1. Missing parts count as 0.
+func CompareVersions(a, b string) int {
+	as, bs := strings.Split(a, "."), strings.Split(b, ".")
+	for i := 0; i < len(as) || i < len(bs); i++ {
+		var x, y int
+		if i < len(as) {
+			x, _ = strconv.Atoi(as[i])
+		}
+		if i < len(bs) {
+			y, _ = strconv.Atoi(bs[i])
+		}
+		switch {
+		case x < y:
+			return -1
+		case x > y:
+			return 1
+		}
+	}
+	return 0
+}
@@ -0,0 +1,30 @@
+package config
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestCompareVersions(t *testing.T) {
+	require := require.New(t)
+	require.Equal(0, CompareVersions("3.0", "3.0.0.0"))
+	require.Equal(1, CompareVersions("4.0.1.929", "3.0"))
+	require.Equal(-1, CompareVersions("0.3.10.2287", "1.0"))
+	require.Equal(1, CompareVersions("5.10.0", "5.9.4"))
+}
+
+func TestSupports(t *testing.T) {
+	require := require.New(t)
+	c := &ArrConfig{App: "radarr"}
+	require.True(c.Supports(FeatureWantedCutoff), "assumed until the version is detected")
+
+	c.SetAPI(&APIInfo{App: "radarr", Version: "4.7.5.7809"})
+	require.False(c.Supports(FeatureWantedCutoff))
+
+	c.SetAPI(&APIInfo{App: "radarr", Version: "5.2.6.8376"})
+	require.True(c.Supports(FeatureWantedCutoff))
+
+	c = &ArrConfig{App: "prowlarr"}
+	require.False(c.Supports(FeatureWantedCutoff), "unknown for the app")
+}
@@ -0,0 +1,108 @@
+package config
+
+import (
+	"errors"
+	"fmt"
+	"path/filepath"
+	"strings"
+
+	"github.com/knadh/koanf/providers/file"
+	"github.com/knadh/koanf/v2"
+	"gopkg.in/yaml.v3"
+
+	base_config "github.com/onedr0p/exportarr/internal/config"
+	sabnzbd_config "github.com/onedr0p/exportarr/internal/sabnzbd/config"
+)
+
+// loadAppConfig merges the api key and URL from the app's own config file at
+// path into k, see base_config.MergeAppConfig.
+func loadAppConfig(k *koanf.Koanf, app string, path string, baseURL string) error {
+	parser, err := appConfigParser(app, path)
+	if err != nil {
+		return err
+	}
+	return k.Load(file.Provider(path), parser, koanf.WithMergeFunc(base_config.MergeAppConfig(baseURL)))
+}
+
+// appConfigParser returns the parser of the config file of app: config.yaml or
+// config.ini for Bazarr, sabnzbd.ini for SABnzbd and config.xml for the others.
+func appConfigParser(app string, path string) (koanf.Parser, error) {
+	ext := strings.ToLower(filepath.Ext(path))
+	switch app {
+	case "sabnzbd":
+		return sabnzbd_config.INIParser(), nil
+	case "bazarr":
+		switch ext {
+		case ".yaml", ".yml":
+			return BazarrYAMLParser(), nil
+		case ".ini":
+			return BazarrINIParser(), nil
+		default:
+			return nil, fmt.Errorf("config must be Bazarr's config.yaml or config.ini")
+		}
+	default:
+		return XMLParser(), nil
+	}
+}
+
+type bazarrYAML struct {
+	Auth struct {
+		ApiKey string `yaml:"apikey"`
+	} `yaml:"auth"`
+	General struct {
+		BaseURL      string `yaml:"base_url"`
+		IP           string `yaml:"ip"`
+		Port         string `yaml:"port"`
+		InstanceName string `yaml:"instance_name"`
+	} `yaml:"general"`
+}
+
+// BazarrYAML parses Bazarr's config/config.yaml.
+type BazarrYAML struct{}
+
+func BazarrYAMLParser() *BazarrYAML {
+	return &BazarrYAML{}
+}
+
+func (p *BazarrYAML) Unmarshal(b []byte) (map[string]interface{}, error) {
+	var config bazarrYAML
+	if err := yaml.Unmarshal(b, &config); err != nil {
+		return nil, err
+	}
+	return map[string]interface{}{
+		"api-key":       config.Auth.ApiKey,
+		"url-base":      config.General.BaseURL,
+		"target-port":   config.General.Port,
+		"bind-address":  config.General.IP,
+		"instance-name": config.General.InstanceName,
+	}, nil
+}
+
+func (p *BazarrYAML) Marshal(o map[string]interface{}) ([]byte, error) {
+	return nil, errors.New("not implemented")
+}
+
+// BazarrINI parses Bazarr's legacy config/config.ini.
+type BazarrINI struct{}
+
+func BazarrINIParser() *BazarrINI {
+	return &BazarrINI{}
+}
+
+func (p *BazarrINI) Unmarshal(b []byte) (map[string]interface{}, error) {
+	sections := base_config.ParseINI(b)
+	general, auth := sections["general"], sections["auth"]
+	if general == nil && auth == nil {
+		return nil, fmt.Errorf("no [general] or [auth] section found")
+	}
+	return map[string]interface{}{
+		"api-key":      auth["apikey"],
+		"url-base":     general["base_url"],
+		"target-port":  general["port"],
+		"bind-address": general["ip"],
+	}, nil
+}
+
+func (p *BazarrINI) Marshal(o map[string]interface{}) ([]byte, error) {
+	return nil, errors.New("not implemented")
+}
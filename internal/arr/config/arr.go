@@ -4,25 +4,32 @@ import (
 	"fmt"
 	"net/url"
 	"strings"
+	"sync/atomic"
+	"time"
 
 	"github.com/gookit/validate"
 	"github.com/knadh/koanf/providers/confmap"
-	"github.com/knadh/koanf/providers/env"
-	"github.com/knadh/koanf/providers/file"
 	"github.com/knadh/koanf/providers/posflag"
 	"github.com/knadh/koanf/v2"
 	flag "github.com/spf13/pflag"
+	"golang.org/x/exp/slices"
 
+	"github.com/onedr0p/exportarr/internal/client"
 	base_config "github.com/onedr0p/exportarr/internal/config"
 )
 
 func RegisterArrFlags(flags *flag.FlagSet) {
-	flags.StringP("config", "c", "", "*arr config.xml file for parsing authentication information")
+	flags.StringP("config", "c", "", "*arr config.xml, or Bazarr config.yaml, file for parsing authentication information")
 	flags.String("auth-username", "", "Username for basic or form auth")
 	flags.String("auth-password", "", "Password for basic or form auth")
+	flags.String("auth-password-file", "", "File containing the password for basic or form auth")
 	flags.Bool("form-auth", false, "Use form based authentication")
 	flags.Bool("enable-unknown-queue-items", false, "Enable unknown queue items")
 	flags.Bool("enable-additional-metrics", false, "Enable additional metrics")
+	flags.StringSlice("collectors", nil, "Only enable these collectors, e.g. queue,history (default all)")
+	flags.StringSlice("disable-collectors", nil, "Disable these collectors, e.g. history,rootfolder")
+	flags.Bool("history-backfill", false, "Count all history events, not only those since exportarr started")
+	flags.String("history-backfill-since", "", "Date from which to count history events")
 
 	// Backwards Compatibility - normalize function will hide these from --help. remove in v2.0.0
 	flags.String("basic-auth-username", "", "Username for basic or form auth")
@@ -31,23 +38,77 @@ func RegisterArrFlags(flags *flag.FlagSet) {
 }
 
 type ArrConfig struct {
-	App                     string         `koanf:"app"`
-	ApiVersion              string         `koanf:"api-version"`
-	XMLConfig               string         `koanf:"config"`
-	AuthUsername            string         `koanf:"auth-username"`
-	AuthPassword            string         `koanf:"auth-password"`
-	FormAuth                bool           `koanf:"form-auth"`
-	EnableUnknownQueueItems bool           `koanf:"enable-unknown-queue-items"`
-	EnableAdditionalMetrics bool           `koanf:"enable-additional-metrics"`
-	URL                     string         `koanf:"url" validate:"required|url"`                        // stores rendered Arr URL (with api version)
-	ApiKey                  string         `koanf:"api-key" validate:"required|regex:(^[a-z0-9]{32}$)"` // stores the API key
-	ApiRootPath             string         `koanf:"api-root-path"`                                      // stores the API root path
-	DisableSSLVerify        bool           `koanf:"disable-ssl-verify"`                                 // stores the disable SSL verify flag
-	Prowlarr                ProwlarrConfig `koanf:"prowlarr"`
-	Bazarr                  BazarrConfig   `koanf:"bazarr"`
+	App                     string                   `koanf:"app"`
+	ApiVersion              string                   `koanf:"api-version"`
+	XMLConfig               string                   `koanf:"config"`
+	InstanceName            string                   `koanf:"instance-name"` // set from config.xml
+	AuthUsername            string                   `koanf:"auth-username"`
+	AuthPassword            string                   `koanf:"auth-password"`
+	AuthPasswordFile        string                   `koanf:"auth-password-file"`
+	FormAuth                bool                     `koanf:"form-auth"`
+	EnableUnknownQueueItems bool                     `koanf:"enable-unknown-queue-items"`
+	EnableAdditionalMetrics bool                     `koanf:"enable-additional-metrics"`
+	Collectors              []string                 `koanf:"collectors"`
+	DisableCollectors       []string                 `koanf:"disable-collectors"`
+	HistoryBackfill         bool                     `koanf:"history-backfill"`
+	HistoryBackfillSince    string                   `koanf:"history-backfill-since" validate:"date"`
+	URL                     string                   `koanf:"url" validate:"required|url"`                        // stores rendered Arr URL (with api version)
+	ApiKey                  string                   `koanf:"api-key" validate:"required|regex:(^[a-z0-9]{32}$)"` // stores the API key
+	ApiRootPath             string                   `koanf:"api-root-path"`                                      // stores the API root path
+	DisableSSLVerify        bool                     `koanf:"disable-ssl-verify"`                                 // stores the disable SSL verify flag
+	CAFile                  string                   `koanf:"ca-file"`
+	ClientCert              string                   `koanf:"client-cert"`
+	ClientKey               string                   `koanf:"client-key"`
+	TLSServerName           string                   `koanf:"tls-server-name"`
+	TLSPinSHA256            []string                 `koanf:"tls-pin-sha256"`
+	ProxyURL                string                   `koanf:"proxy-url"`
+	Headers                 map[string]string        `koanf:"headers"`
+	OAuth2TokenURL          string                   `koanf:"oauth2-token-url" validate:"url"`
+	OAuth2ClientID          string                   `koanf:"oauth2-client-id"`
+	OAuth2Secret            string                   `koanf:"oauth2-client-secret"`
+	OAuth2SecretFile        string                   `koanf:"oauth2-client-secret-file"`
+	OAuth2Scopes            []string                 `koanf:"oauth2-scopes"`
+	Prowlarr                ProwlarrConfig           `koanf:"prowlarr"`
+	Bazarr                  BazarrConfig             `koanf:"bazarr"`
+	RetryPolicy             *client.RetryPolicy      `koanf:"-"` // nil to use client.DefaultRetryPolicy
+	Clients                 *client.Pool             `koanf:"-"` // holds the client shared by the collectors, nil for throwaway configs
+	api                     *atomic.Pointer[APIInfo] // detected from the app, see API
 	k                       *koanf.Koanf
 }
 
+// Collectors available for each app, named after the app itself or what they collect.
+var AppCollectors = map[string][]string{
+	"radarr":   {"radarr", "queue", "cutoff", "missing", "history", "rootfolder", "status", "health"},
+	"sonarr":   {"sonarr", "queue", "cutoff", "missing", "history", "rootfolder", "status", "health"},
+	"lidarr":   {"lidarr", "queue", "cutoff", "missing", "history", "rootfolder", "status", "health"},
+	"readarr":  {"readarr", "queue", "cutoff", "missing", "history", "rootfolder", "status", "health"},
+	"bazarr":   {"bazarr"},
+	"prowlarr": {"prowlarr", "history", "status", "health"},
+}
+
+// Collectors which page through a whole list of the app on every collection,
+// so they're only enabled when named in the collectors setting.
+var OptInCollectors = []string{"cutoff", "missing"}
+
+// CollectorEnabled reports whether the named collector is enabled by the
+// collectors and disable-collectors settings.
+func (c *ArrConfig) CollectorEnabled(name string) bool {
+	if len(c.Collectors) > 0 && !slices.Contains(c.Collectors, name) {
+		return false
+	}
+	if len(c.Collectors) == 0 && slices.Contains(OptInCollectors, name) {
+		return false
+	}
+	return !slices.Contains(c.DisableCollectors, name)
+}
+
+// HistoryBackfillTime returns the date of history-backfill-since, zero
+// when it isn't set.
+func (c *ArrConfig) HistoryBackfillTime() time.Time {
+	t, _ := time.Parse("2006-01-02", c.HistoryBackfillSince)
+	return t
+}
+
 func (c *ArrConfig) UseBasicAuth() bool {
 	return !c.FormAuth && c.AuthUsername != "" && c.AuthPassword != ""
 }
@@ -61,38 +122,36 @@ func (c *ArrConfig) BaseURL() string {
 	return ret
 }
 
-func LoadArrConfig(conf base_config.Config, flags *flag.FlagSet) (*ArrConfig, error) {
-	k := koanf.New(".")
-
-	// Defaults
-	err := k.Load(confmap.Provider(map[string]interface{}{
-		"api-version": "v3",
-	}, "."), nil)
+// ConfigPath returns the path of the app's own config file, e.g. config.xml,
+// from the config file, the environment and flags as LoadArrConfig reads it.
+func ConfigPath(conf base_config.Config, flags *flag.FlagSet) (string, error) {
+	k, _, err := loadArrKoanf(conf, flags)
 	if err != nil {
-		return nil, err
+		return "", err
 	}
+	return k.String("config"), nil
+}
 
-	// Environment
-	err = k.Load(env.Provider("", ".", func(s string) string {
-		s = strings.ToLower(s)
-		s = strings.Replace(s, "__", ".", -1)
-		s = strings.Replace(s, "_", "-", -1)
-		return backwardsCompatibilityTransforms(s)
-	}), nil)
+func LoadArrConfig(conf base_config.Config, flags *flag.FlagSet) (*ArrConfig, error) {
+	k, deprecatedEnv, err := loadArrKoanf(conf, flags)
 	if err != nil {
 		return nil, err
 	}
+	base_config.LogDeprecatedEnv(deprecatedEnv)
 
-	// Flags
-	if err := k.Load(posflag.Provider(flags, ".", k), nil); err != nil {
-		return nil, err
+	// Base settings were already resolved by LoadConfig.
+	for _, key := range base_config.Keys(base_config.Config{}) {
+		k.Delete(key)
+	}
+
+	// Secrets
+	if err := conf.Secrets().Resolve(k, "auth-password"); err != nil {
+		return nil, conf.File().Explain(err)
 	}
 
-	// XMLConfig
-	xmlConfig := k.String("config")
-	if xmlConfig != "" {
-		err := k.Load(file.Provider(xmlConfig), XMLParser(), koanf.WithMergeFunc(XMLParser().Merge(conf.URL)))
-		if err != nil {
+	// The app's own config file
+	if path := k.String("config"); path != "" {
+		if err := loadAppConfig(k, conf.App, path, conf.URL); err != nil {
 			return nil, err
 		}
 	}
@@ -103,14 +162,96 @@ func LoadArrConfig(conf base_config.Config, flags *flag.FlagSet) (*ArrConfig, er
 		ApiKey:           conf.ApiKey,
 		ApiRootPath:      conf.ApiRootPath,
 		DisableSSLVerify: conf.DisableSSLVerify,
+		CAFile:           conf.CAFile,
+		ClientCert:       conf.ClientCert,
+		ClientKey:        conf.ClientKey,
+		TLSServerName:    conf.TLSServerName,
+		TLSPinSHA256:     conf.TLSPinSHA256,
+		ProxyURL:         conf.ProxyURL,
+		Headers:          conf.Headers,
+		OAuth2TokenURL:   conf.OAuth2TokenURL,
+		OAuth2ClientID:   conf.OAuth2ClientID,
+		OAuth2Secret:     conf.OAuth2Secret,
+		OAuth2SecretFile: conf.OAuth2SecretFile,
+		OAuth2Scopes:     conf.OAuth2Scopes,
+		RetryPolicy:      conf.RetryPolicy(),
+		api:              &atomic.Pointer[APIInfo]{},
 		k:                k,
 	}
 	if err = k.Unmarshal("", out); err != nil {
-		return nil, err
+		return nil, conf.File().Explain(err)
 	}
+	out.Collectors = base_config.SplitList(out.Collectors)
+	out.DisableCollectors = base_config.SplitList(out.DisableCollectors)
 	return out, nil
 }
 
+// loadArrKoanf loads the settings of an app from its defaults, the config
+// file, the environment and flags, in increasing order of precedence.
+func loadArrKoanf(conf base_config.Config, flags *flag.FlagSet) (*koanf.Koanf, []base_config.DeprecatedEnv, error) {
+	k := koanf.New(".")
+
+	// Defaults
+	err := k.Load(confmap.Provider(map[string]interface{}{
+		"api-version": "v3",
+	}, "."), nil)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	// Config File
+	if f := conf.File(); f != nil {
+		if err := k.Merge(f.Koanf()); err != nil {
+			return nil, nil, err
+		}
+		f.ShadowFlags(flags)
+	}
+
+	// Environment
+	envK, deprecatedEnv, err := base_config.LoadEnv(base_config.EnvOptions{
+		App:       conf.App,
+		Keys:      arrKeys(),
+		Legacy:    !conf.DisableLegacyEnv,
+		Transform: backwardsCompatibilityTransforms,
+	})
+	if err != nil {
+		return nil, nil, err
+	}
+	if err := k.Merge(envK); err != nil {
+		return nil, nil, err
+	}
+	conf.File().Shadow(envK.Keys()...)
+
+	// Flags
+	if err := k.Load(posflag.Provider(flags, ".", k), nil); err != nil {
+		return nil, nil, err
+	}
+	return k, deprecatedEnv, nil
+}
+
+// Files returns the files the app's settings were read from, beyond those of the base config.
+func (c *ArrConfig) Files() []string {
+	var ret []string
+	for _, file := range []string{c.XMLConfig, c.AuthPasswordFile} {
+		if file != "" {
+			ret = append(ret, file)
+		}
+	}
+	return ret
+}
+
+// TLS returns the settings verifying the app's certificate.
+func (c *ArrConfig) TLS() base_config.TLSConfig {
+	return base_config.TLSConfig{
+		InsecureSkipVerify: c.DisableSSLVerify,
+		CAFile:             c.CAFile,
+		CertFile:           c.ClientCert,
+		KeyFile:            c.ClientKey,
+		ServerName:         c.TLSServerName,
+		PinnedSHA256:       c.TLSPinSHA256,
+	}
+}
+
 func (c *ArrConfig) Validate() error {
 	v := validate.Struct(c)
 	if !v.Validate() {
@@ -126,13 +267,35 @@ func (c *ArrConfig) Validate() error {
 	if c.FormAuth && (c.AuthUsername == "" || c.AuthPassword == "") {
 		return fmt.Errorf("auth-username and auth-password are required when form-auth is set")
 	}
+	if c.OAuth2TokenURL != "" && c.OAuth2ClientID == "" {
+		return fmt.Errorf("oauth2-client-id is required when oauth2-token-url is set")
+	}
+	if c.OAuth2TokenURL != "" && c.UseBasicAuth() {
+		return fmt.Errorf("oauth2-token-url can't be combined with basic auth, both set the Authorization header")
+	}
+	if known, ok := AppCollectors[c.App]; ok {
+		for _, name := range c.Collectors {
+			if !slices.Contains(known, name) {
+				return fmt.Errorf("collectors: unknown collector %s, must be one of: %s", name, strings.Join(known, ", "))
+			}
+		}
+		for _, name := range c.DisableCollectors {
+			if !slices.Contains(known, name) {
+				return fmt.Errorf("disable-collectors: unknown collector %s, must be one of: %s", name, strings.Join(known, ", "))
+			}
+		}
+	}
 
-	return nil
+	if err := base_config.ValidateUpstream(c.URL, c.ProxyURL); err != nil {
+		return err
+	}
+	return c.TLS().Validate()
 }
 
 func (c ArrConfig) Messages() map[string]string {
 	return validate.MS{
 		"ApiKey.regex":              "api-key must be a 20-32 character alphanumeric string",
+		"HistoryBackfillSince.date": "history-backfill-since must be in the format YYYY-MM-DD",
 		"LogLevel.ValidateLogLevel": "log-level must be one of: debug, info, warn, error, dpanic, panic, fatal",
 	}
 }
@@ -143,11 +306,41 @@ func (c ArrConfig) Translates() map[string]string {
 		"XMLConfig":               "config",
 		"AuthUsername":            "auth-username",
 		"AuthPassword":            "auth-password",
+		"AuthPasswordFile":        "auth-password-file",
 		"ApiRootPath":             "api-root-path",
+		"DisableSSLVerify":        "disable-ssl-verify",
+		"CAFile":                  "ca-file",
+		"ClientCert":              "client-cert",
+		"ClientKey":               "client-key",
+		"TLSServerName":           "tls-server-name",
+		"TLSPinSHA256":            "tls-pin-sha256",
+		"ProxyURL":                "proxy-url",
+		"Headers":                 "headers",
+		"OAuth2TokenURL":          "oauth2-token-url",
+		"OAuth2ClientID":          "oauth2-client-id",
+		"OAuth2Secret":            "oauth2-client-secret",
+		"OAuth2SecretFile":        "oauth2-client-secret-file",
+		"OAuth2Scopes":            "oauth2-scopes",
 		"FormAuth":                "form-auth",
 		"EnableUnknownQueueItems": "enable-unknown-queue-items",
 		"EnableAdditionalMetrics": "enable-additional-metrics",
+		"Collectors":              "collectors",
+		"DisableCollectors":       "disable-collectors",
+		"HistoryBackfill":         "history-backfill",
+		"HistoryBackfillSince":    "history-backfill-since",
+	}
+}
+
+// arrKeys returns the settings of ArrConfig which aren't base settings.
+func arrKeys() []string {
+	base := base_config.Keys(base_config.Config{})
+	var ret []string
+	for _, key := range base_config.Keys(ArrConfig{}) {
+		if !slices.Contains(base, key) {
+			ret = append(ret, key)
+		}
 	}
+	return ret
 }
 
 // Remove in v2.0.0
@@ -22,7 +22,7 @@ func TestArrConfigIntegration(t *testing.T) {
 	t.Setenv("ENABLE_ADDITIONAL_METRICS", "true")
 	t.Setenv("ENABLE_UNKNOWN_QUEUE_ITEMS", "false")
 
-	config, err := base_config.LoadConfig(configFlags)
+	config, err := base_config.LoadConfig("sonarr", configFlags)
 	require.NoError(err)
 	arrConfig, err := LoadArrConfig(*config, arrConfigFlags)
 	require.NoError(err)
@@ -2,7 +2,9 @@ package config
 
 import (
 	"testing"
+	"time"
 
+	"github.com/onedr0p/exportarr/internal/client"
 	base_config "github.com/onedr0p/exportarr/internal/config"
 	"github.com/spf13/pflag"
 	"github.com/stretchr/testify/require"
@@ -58,6 +60,21 @@ func TestLoadConfig_Defaults(t *testing.T) {
 	require.True(config.DisableSSLVerify)
 }
 
+func TestLoadConfig_RetryPolicy(t *testing.T) {
+	require := require.New(t)
+	c := base_config.Config{
+		URL:             "http://localhost",
+		ApiKey:          "abcdef0123456789abcdef0123456789",
+		RetryAttempts:   5,
+		RetryBackoff:    time.Second,
+		RetryMaxBackoff: time.Minute,
+	}
+
+	config, err := LoadArrConfig(c, testFlagSet())
+	require.NoError(err)
+	require.Equal(&client.RetryPolicy{Attempts: 5, Backoff: time.Second, MaxBackoff: time.Minute}, config.RetryPolicy)
+}
+
 func TestLoadConfig_Environment(t *testing.T) {
 	flags := testFlagSet()
 	c := base_config.Config{
@@ -146,6 +163,26 @@ func TestLoadConfig_Flags(t *testing.T) {
 	require.Equal("v3", config.ApiVersion)
 }
 
+func TestLoadConfig_AuthPasswordFile(t *testing.T) {
+	flags := testFlagSet()
+	flags.Set("auth-username", "user")
+	flags.Set("auth-password", "pass")
+	flags.Set("auth-password-file", "test_fixtures/auth_password")
+	c := base_config.Config{}
+
+	require := require.New(t)
+	config, err := LoadArrConfig(c, flags)
+	require.NoError(err)
+	require.Equal("file-password", config.AuthPassword)
+	require.Contains(config.Files(), "test_fixtures/auth_password")
+
+	flags.Set("auth-password-file", "")
+	flags.Set("auth-password", "exec:echo exec-password")
+	config, err = LoadArrConfig(c, flags)
+	require.NoError(err)
+	require.Equal("exec-password", config.AuthPassword)
+}
+
 func TestLoadConfig_XMLConfig(t *testing.T) {
 	flags := testFlagSet()
 	flags.Set("config", "test_fixtures/config.test_xml")
@@ -180,6 +217,145 @@ func TestLoadConfig_XMLConfigEnv(t *testing.T) {
 	require.Equal("abcdef0123456789abcdef0123456789", config.ApiKey)
 }
 
+func TestLoadConfig_XMLConfigSSL(t *testing.T) {
+	flags := testFlagSet()
+	flags.Set("config", "test_fixtures/config_ssl.test_xml")
+	flags.Set("auth-username", "user")
+	flags.Set("auth-password", "pass")
+
+	config, err := LoadArrConfig(base_config.Config{}, flags)
+
+	require := require.New(t)
+	require.NoError(err)
+
+	// Without a url, the app is reached at its bind address over SSL
+	require.Equal("https://192.168.1.10:9898/sonarr", config.URL)
+	require.Equal("Sonarr 4K", config.InstanceName)
+	require.True(config.UseFormAuth(), "The app's authentication method should be used")
+}
+
+func TestLoadConfig_BazarrConfig(t *testing.T) {
+	for _, path := range []string{"test_fixtures/bazarr_config.yaml", "test_fixtures/bazarr_config.ini"} {
+		t.Run(path, func(t *testing.T) {
+			flags := testFlagSet()
+			flags.Set("config", path)
+
+			config, err := LoadArrConfig(base_config.Config{App: "bazarr"}, flags)
+
+			require := require.New(t)
+			require.NoError(err)
+			require.Equal("http://localhost:6767/bazarr", config.URL)
+			require.Equal("abcdef0123456789abcdef0123456789", config.ApiKey)
+		})
+	}
+
+	flags := testFlagSet()
+	flags.Set("config", "test_fixtures/config.test_xml")
+	_, err := LoadArrConfig(base_config.Config{App: "bazarr"}, flags)
+	require.Error(t, err, "Bazarr has no config.xml")
+}
+
+func TestLoadConfig_Collectors(t *testing.T) {
+	c := base_config.Config{
+		App:    "sonarr",
+		URL:    "http://localhost",
+		ApiKey: "abcdef0123456789abcdef0123456789",
+	}
+	require := require.New(t)
+
+	t.Setenv("COLLECTORS", "queue, history")
+	config, err := LoadArrConfig(c, testFlagSet())
+	require.NoError(err)
+	require.Equal([]string{"queue", "history"}, config.Collectors)
+	require.True(config.CollectorEnabled("queue"))
+	require.False(config.CollectorEnabled("sonarr"))
+	require.False(config.CollectorEnabled("cutoff"))
+
+	t.Setenv("COLLECTORS", "sonarr,cutoff")
+	config, err = LoadArrConfig(c, testFlagSet())
+	require.NoError(err)
+	require.True(config.CollectorEnabled("cutoff"))
+	require.False(config.CollectorEnabled("missing"))
+
+	flags := testFlagSet()
+	flags.Set("collectors", "")
+	flags.Set("disable-collectors", "history,rootfolder")
+	config, err = LoadArrConfig(c, flags)
+	require.NoError(err)
+	require.Empty(config.Collectors)
+	require.True(config.CollectorEnabled("sonarr"))
+	require.False(config.CollectorEnabled("history"))
+	require.False(config.CollectorEnabled("rootfolder"))
+	require.False(config.CollectorEnabled("cutoff"))
+	require.False(config.CollectorEnabled("missing"))
+}
+
+func TestLoadConfig_PrefixedEnvironment(t *testing.T) {
+	c := base_config.Config{
+		App:    "sonarr",
+		URL:    "http://localhost",
+		ApiKey: "abcdef0123456789abcdef0123456789",
+	}
+	require := require.New(t)
+	t.Setenv("AUTH_USERNAME", "legacy")
+	t.Setenv("EXPORTARR_AUTH_PASSWORD", "pass")
+	t.Setenv("EXPORTARR_SONARR__AUTH_USERNAME", "user")
+	t.Setenv("EXPORTARR_RADARR__AUTH_USERNAME", "radarr")
+	t.Setenv("EXPORTARR_URL", "http://ignored")
+
+	config, err := LoadArrConfig(c, testFlagSet())
+	require.NoError(err)
+	require.Equal("user", config.AuthUsername)
+	require.Equal("pass", config.AuthPassword)
+	require.Equal("http://localhost", config.URL)
+
+	c.DisableLegacyEnv = true
+	c.App = "radarr"
+	t.Setenv("ENABLE_ADDITIONAL_METRICS", "true")
+	config, err = LoadArrConfig(c, testFlagSet())
+	require.NoError(err)
+	require.Equal("radarr", config.AuthUsername)
+	require.False(config.EnableAdditionalMetrics)
+}
+
+func TestLoadConfig_File(t *testing.T) {
+	require := require.New(t)
+	baseFlags := pflag.NewFlagSet("test", pflag.ContinueOnError)
+	base_config.RegisterConfigFlags(baseFlags)
+	baseFlags.Set("config-file", "test_fixtures/config.yaml")
+
+	t.Setenv("PROWLARR__BACKFILL_SINCE_DATE", "2023-04-01")
+	c, err := base_config.LoadConfig("prowlarr", baseFlags)
+	require.NoError(err)
+	flags := testFlagSet()
+	RegisterProwlarrFlags(flags)
+	config, err := LoadArrConfig(*c, flags)
+	require.NoError(err)
+	require.NoError(config.LoadProwlarrConfig(flags))
+
+	require.Equal("http://prowlarr:9696", config.URL)
+	require.True(config.EnableUnknownQueueItems)
+	require.True(config.EnableAdditionalMetrics)
+	require.True(config.Prowlarr.Backfill)
+	require.Equal("2023-04-01", config.Prowlarr.BackfillSinceDate)
+
+	// Flags override the file, base settings come from the base config
+	baseFlags.Set("url", "http://localhost:6767")
+	c, err = base_config.LoadConfig("bazarr", baseFlags)
+	require.NoError(err)
+	flags = testFlagSet()
+	RegisterBazarrFlags(flags)
+	flags.Set("series-batch-concurrency", "2")
+	config, err = LoadArrConfig(*c, flags)
+	require.NoError(err)
+	require.NoError(config.LoadBazarrConfig(flags))
+
+	require.Equal("http://localhost:6767", config.URL)
+	require.False(config.EnableAdditionalMetrics)
+	require.Equal(50, config.Bazarr.SeriesBatchSize)
+	require.Equal(2, config.Bazarr.SeriesBatchConcurrency)
+}
+
 func TestValidate(t *testing.T) {
 	params := []struct {
 		name   string
@@ -209,6 +385,26 @@ func TestValidate(t *testing.T) {
 			},
 			valid: true,
 		},
+		{
+			name: "good-history-backfill-since",
+			config: &ArrConfig{
+				URL:                  "http://localhost",
+				ApiKey:               "abcdef0123456789abcdef0123456789",
+				ApiVersion:           "v3",
+				HistoryBackfillSince: "2023-03-01",
+			},
+			valid: true,
+		},
+		{
+			name: "bad-history-backfill-since",
+			config: &ArrConfig{
+				URL:                  "http://localhost",
+				ApiKey:               "abcdef0123456789abcdef0123456789",
+				ApiVersion:           "v3",
+				HistoryBackfillSince: "03/01/2023",
+			},
+			valid: false,
+		},
 		{
 			name: "good-api-key-32-len",
 			config: &ArrConfig{
@@ -245,6 +441,37 @@ func TestValidate(t *testing.T) {
 			},
 			valid: true,
 		},
+		{
+			name: "known-collectors",
+			config: &ArrConfig{
+				App:               "prowlarr",
+				URL:               "http://localhost",
+				ApiKey:            "abcdef0123456789abcdef0123456789",
+				Collectors:        []string{"prowlarr", "history"},
+				DisableCollectors: []string{"health"},
+			},
+			valid: true,
+		},
+		{
+			name: "unknown-collector",
+			config: &ArrConfig{
+				App:        "prowlarr",
+				URL:        "http://localhost",
+				ApiKey:     "abcdef0123456789abcdef0123456789",
+				Collectors: []string{"queue"},
+			},
+			valid: false,
+		},
+		{
+			name: "unknown-disabled-collector",
+			config: &ArrConfig{
+				App:               "sonarr",
+				URL:               "http://localhost",
+				ApiKey:            "abcdef0123456789abcdef0123456789",
+				DisableCollectors: []string{"library"},
+			},
+			valid: false,
+		},
 		{
 			name: "password-needs-username",
 			config: &ArrConfig{
@@ -275,6 +502,72 @@ func TestValidate(t *testing.T) {
 			},
 			valid: false,
 		},
+		{
+			name: "oauth2-with-form-auth",
+			config: &ArrConfig{
+				URL:            "http://localhost",
+				ApiKey:         "abcdef0123456789abcdef0123456789",
+				ApiVersion:     "v3",
+				AuthUsername:   "username",
+				AuthPassword:   "password",
+				FormAuth:       true,
+				OAuth2TokenURL: "https://auth.example.com/token",
+				OAuth2ClientID: "exportarr",
+			},
+			valid: true,
+		},
+		{
+			name: "oauth2-with-basic-auth",
+			config: &ArrConfig{
+				URL:            "http://localhost",
+				ApiKey:         "abcdef0123456789abcdef0123456789",
+				ApiVersion:     "v3",
+				AuthUsername:   "username",
+				AuthPassword:   "password",
+				OAuth2TokenURL: "https://auth.example.com/token",
+				OAuth2ClientID: "exportarr",
+			},
+			valid: false,
+		},
+		{
+			name: "oauth2-needs-client-id",
+			config: &ArrConfig{
+				URL:            "http://localhost",
+				ApiKey:         "abcdef0123456789abcdef0123456789",
+				ApiVersion:     "v3",
+				OAuth2TokenURL: "https://auth.example.com/token",
+			},
+			valid: false,
+		},
+		{
+			name: "socks5-proxy",
+			config: &ArrConfig{
+				URL:        "http://sonarr:8989",
+				ApiKey:     "abcdef0123456789abcdef0123456789",
+				ApiVersion: "v3",
+				ProxyURL:   "socks5://proxy:1080",
+			},
+			valid: true,
+		},
+		{
+			name: "socket",
+			config: &ArrConfig{
+				URL:        "unix:///run/sonarr.sock",
+				ApiKey:     "abcdef0123456789abcdef0123456789",
+				ApiVersion: "v3",
+			},
+			valid: true,
+		},
+		{
+			name: "socket-with-proxy",
+			config: &ArrConfig{
+				URL:        "unix:///run/sonarr.sock",
+				ApiKey:     "abcdef0123456789abcdef0123456789",
+				ApiVersion: "v3",
+				ProxyURL:   "http://proxy:3128",
+			},
+			valid: false,
+		},
 	}
 	for _, p := range params {
 		t.Run(p.name, func(t *testing.T) {
@@ -41,8 +41,16 @@ func (b BazarrConfig) Translate() map[string]string {
 }
 
 func (c *ArrConfig) LoadBazarrConfig(flags *flag.FlagSet) error {
+	// Flags override the settings from the config file and environment.
 	err := c.k.Load(posflag.Provider(flags, ".", c.k), nil, koanf.WithMergeFunc(func(src, dest map[string]interface{}) error {
-		dest["bazarr"] = src
+		settings, ok := dest["bazarr"].(map[string]interface{})
+		if !ok {
+			settings = map[string]interface{}{}
+		}
+		for key, val := range src {
+			settings[key] = val
+		}
+		dest["bazarr"] = settings
 		return nil
 	}))
 	if err != nil {
@@ -45,8 +45,16 @@ func (p ProwlarrConfig) Translates() map[string]string {
 }
 
 func (c *ArrConfig) LoadProwlarrConfig(flags *flag.FlagSet) error {
+	// Flags override the settings from the config file and environment.
 	err := c.k.Load(posflag.Provider(flags, ".", c.k), nil, koanf.WithMergeFunc(func(src, dest map[string]interface{}) error {
-		dest["prowlarr"] = src
+		settings, ok := dest["prowlarr"].(map[string]interface{})
+		if !ok {
+			settings = map[string]interface{}{}
+		}
+		for key, val := range src {
+			settings[key] = val
+		}
+		dest["prowlarr"] = settings
 		return nil
 	}))
 	if err != nil {
@@ -0,0 +1,284 @@
+package config
+
+import (
+	"fmt"
+	neturl "net/url"
+	"path"
+	"strings"
+	"sync/atomic"
+
+	"github.com/knadh/koanf/parsers/yaml"
+	"github.com/knadh/koanf/providers/file"
+	"github.com/knadh/koanf/v2"
+	"golang.org/x/exp/maps"
+	"golang.org/x/exp/slices"
+
+	base_config "github.com/onedr0p/exportarr/internal/config"
+)
+
+// Apps that can be used as the `app` of a target.
+var TargetApps = []string{"radarr", "sonarr", "lidarr", "readarr", "prowlarr", "bazarr", "sabnzbd"}
+
+// Target is a single instance scraped by `exportarr serve`.
+type Target struct {
+	Name       string `koanf:"name"`
+	ApiKeyFile string `koanf:"api-key-file"`
+	ArrConfig  `koanf:",squash"`
+}
+
+// TargetsFile is the config file used by `exportarr serve`. It lists the
+// targets to export and the named modules used by the /probe endpoint.
+type TargetsFile struct {
+	Path    string
+	Targets []*Target
+	Errors  []error // Targets which failed to load and were skipped
+	conf    base_config.Config
+	secrets base_config.SecretSource // resolves the secrets of the file once, e.g. on every probe of a module
+	k       *koanf.Koanf
+}
+
+// LoadTargetsFile reads the targets file at path. Each target inherits the base
+// config values it does not set itself. Targets which fail to load are
+// collected in Errors alongside the valid targets so callers can skip them.
+func LoadTargetsFile(conf base_config.Config, path string) (*TargetsFile, error) {
+	k := koanf.New(".")
+	if err := k.Load(file.Provider(path), yaml.Parser()); err != nil {
+		return nil, fmt.Errorf("Couldn't load targets file %s: %w", path, err)
+	}
+
+	ret := &TargetsFile{
+		Path:    path,
+		conf:    conf,
+		secrets: conf.Secrets().Cached(),
+		k:       k,
+	}
+	names := map[string]bool{}
+	for i, tk := range k.Slices("targets") {
+		t, err := loadTarget(conf, ret.secrets, tk)
+		if err != nil {
+			ret.Errors = append(ret.Errors, fmt.Errorf("target %d: %w", i, err))
+			continue
+		}
+		if names[t.Name] {
+			ret.Errors = append(ret.Errors, fmt.Errorf("target %d: duplicate name %s", i, t.Name))
+			continue
+		}
+		names[t.Name] = true
+		ret.Targets = append(ret.Targets, t)
+	}
+	return ret, nil
+}
+
+// Files returns the targets file and the files its targets were read from.
+func (f *TargetsFile) Files() []string {
+	ret := []string{f.Path}
+	for _, t := range f.Targets {
+		ret = append(ret, t.Files()...)
+	}
+	return ret
+}
+
+// Files returns the files the target's settings were read from.
+func (t *Target) Files() []string {
+	ret := append(t.ArrConfig.Files(), t.TLS().Files()...)
+	for _, file := range []string{t.ApiKeyFile, t.OAuth2SecretFile} {
+		if file != "" {
+			ret = append(ret, file)
+		}
+	}
+	return ret
+}
+
+// Modules returns the names of the modules available to ProbeTarget.
+func (f *TargetsFile) Modules() []string {
+	return f.k.MapKeys("modules")
+}
+
+// ProbeTarget builds a target for app at url, using the settings and
+// credentials of the named module. The "default" module is used when
+// module is empty. Since the module's credentials are sent to url, url must
+// match one of the module's allowed-targets, and unix:// urls are only
+// allowed with allow-unix-sockets.
+func (f *TargetsFile) ProbeTarget(app string, url string, module string) (*Target, error) {
+	if module == "" {
+		module = "default"
+	}
+	if !slices.Contains(f.Modules(), module) {
+		return nil, fmt.Errorf("unknown module %s", module)
+	}
+
+	k := f.k.Cut("modules." + module)
+	probe := struct {
+		AllowedTargets   []string `koanf:"allowed-targets"`
+		AllowUnixSockets bool     `koanf:"allow-unix-sockets"`
+	}{}
+	if err := k.Unmarshal("", &probe); err != nil {
+		return nil, err
+	}
+	allowed := base_config.SplitList(probe.AllowedTargets)
+	if err := checkProbeTarget(url, allowed, probe.AllowUnixSockets); err != nil {
+		return nil, fmt.Errorf("module %s: %w", module, err)
+	}
+	for key, val := range map[string]string{"app": app, "url": url, "name": url} {
+		if err := k.Set(key, val); err != nil {
+			return nil, err
+		}
+	}
+	return loadTarget(f.conf, f.secrets, k)
+}
+
+// checkProbeTarget checks rawURL against the allowed-targets of a module.
+// Entries with a scheme are URL prefixes, e.g. http://sonarr:8989/, which
+// match whole path elements only. Other entries are host patterns, e.g.
+// *.media.lan or sonarr:8989, matched with path.Match against the host and
+// port, or the host alone when the pattern has no port.
+func checkProbeTarget(rawURL string, allowed []string, allowUnix bool) error {
+	if len(allowed) == 0 {
+		return fmt.Errorf("allowed-targets must be set to probe targets")
+	}
+	u, err := neturl.Parse(rawURL)
+	if err != nil {
+		return fmt.Errorf("target: %w", err)
+	}
+	switch u.Scheme {
+	case "http", "https":
+	case "unix":
+		if !allowUnix {
+			return fmt.Errorf("unix:// targets need allow-unix-sockets")
+		}
+	default:
+		return fmt.Errorf("target: unsupported scheme %s", u.Scheme)
+	}
+	if u.User != nil {
+		return fmt.Errorf("target: user info isn't allowed")
+	}
+	if u.Path == "" && u.Scheme != "unix" {
+		// http://sonarr:8989 is matched by http://sonarr:8989/
+		root := *u
+		root.Path = "/"
+		rawURL = root.String()
+	}
+
+	for _, entry := range allowed {
+		if strings.Contains(entry, "://") {
+			if !strings.HasPrefix(rawURL, entry) {
+				continue
+			}
+			rest := rawURL[len(entry):]
+			if rest == "" || strings.HasSuffix(entry, "/") || strings.ContainsAny(rest[:1], "/?#") {
+				return nil
+			}
+			continue
+		}
+		if u.Scheme == "unix" {
+			continue
+		}
+		host := u.Hostname()
+		if strings.Contains(entry, ":") {
+			host = u.Host
+		}
+		if ok, _ := path.Match(entry, host); ok {
+			return nil
+		}
+	}
+	return fmt.Errorf("target %s isn't in allowed-targets", u.Redacted())
+}
+
+func loadTarget(conf base_config.Config, secrets base_config.SecretSource, k *koanf.Koanf) (*Target, error) {
+	t := &Target{
+		ArrConfig: ArrConfig{
+			ApiRootPath:      conf.ApiRootPath,
+			DisableSSLVerify: conf.DisableSSLVerify,
+			CAFile:           conf.CAFile,
+			ClientCert:       conf.ClientCert,
+			ClientKey:        conf.ClientKey,
+			TLSServerName:    conf.TLSServerName,
+			TLSPinSHA256:     conf.TLSPinSHA256,
+			ProxyURL:         conf.ProxyURL,
+			Headers:          maps.Clone(conf.Headers), // Unmarshal merges the target's headers into it
+			OAuth2TokenURL:   conf.OAuth2TokenURL,
+			OAuth2ClientID:   conf.OAuth2ClientID,
+			OAuth2Secret:     conf.OAuth2Secret,
+			OAuth2Scopes:     conf.OAuth2Scopes,
+			RetryPolicy:      conf.RetryPolicy(),
+			api:              &atomic.Pointer[APIInfo]{},
+			Bazarr: BazarrConfig{
+				SeriesBatchSize:        300,
+				SeriesBatchConcurrency: 10,
+			},
+			k: k,
+		},
+	}
+	for _, key := range []string{"api-key", "auth-password", "oauth2-client-secret"} {
+		if err := secrets.Resolve(k, key); err != nil {
+			return nil, err
+		}
+	}
+	if err := k.Unmarshal("", t); err != nil {
+		return nil, err
+	}
+	if err := secrets.ResolveValues("headers", t.Headers); err != nil {
+		return nil, err
+	}
+
+	if !slices.Contains(TargetApps, t.App) {
+		return nil, fmt.Errorf("app must be one of: %s", strings.Join(TargetApps, ", "))
+	}
+	t.Collectors = base_config.SplitList(t.Collectors)
+	t.DisableCollectors = base_config.SplitList(t.DisableCollectors)
+	t.TLSPinSHA256 = base_config.SplitList(t.TLSPinSHA256)
+	t.OAuth2Scopes = base_config.SplitList(t.OAuth2Scopes)
+	if t.ApiVersion == "" {
+		t.ApiVersion = DefaultApiVersion(t.App)
+	}
+
+	if t.XMLConfig != "" {
+		if err := loadAppConfig(k, t.App, t.XMLConfig, t.URL); err != nil {
+			return nil, err
+		}
+		t.ApiKey = k.String("api-key")
+		t.URL = k.String("url")
+		t.InstanceName = k.String("instance-name")
+		t.FormAuth = k.Bool("form-auth")
+	}
+	if t.Name == "" {
+		t.Name = t.URL
+	}
+	t.Prowlarr.BackfillSinceTime = k.Time("prowlarr.backfill-since-date", "2006-01-02")
+
+	// SABnzbd api keys don't follow the *arr format, so only the
+	// *arr specific validation is skipped for it.
+	if t.App == "sabnzbd" {
+		if t.URL == "" || t.ApiKey == "" {
+			return nil, fmt.Errorf("%s: url and api-key are required", t.Name)
+		}
+		if err := base_config.ValidateUpstream(t.URL, t.ProxyURL); err != nil {
+			return nil, fmt.Errorf("%s: %w", t.Name, err)
+		}
+		if err := t.TLS().Validate(); err != nil {
+			return nil, fmt.Errorf("%s: %w", t.Name, err)
+		}
+		return t, nil
+	}
+
+	if err := t.ArrConfig.Validate(); err != nil {
+		return nil, fmt.Errorf("%s: %w", t.Name, err)
+	}
+	switch t.App {
+	case "prowlarr":
+		if err := t.Prowlarr.Validate(); err != nil {
+			return nil, fmt.Errorf("%s: %w", t.Name, err)
+		}
+	case "bazarr":
+		if err := t.Bazarr.Validate(); err != nil {
+			return nil, fmt.Errorf("%s: %w", t.Name, err)
+		}
+	}
+	return t, nil
+}
+
+// DefaultApiVersion returns the API version exportarr reads of app, empty for
+// apps whose API isn't versioned.
+func DefaultApiVersion(app string) string {
+	return ApiVersions[app]
+}
@@ -0,0 +1,172 @@
+package config
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	base_config "github.com/onedr0p/exportarr/internal/config"
+	"github.com/stretchr/testify/require"
+)
+
+func TestLoadTargets(t *testing.T) {
+	require := require.New(t)
+	base := base_config.Config{
+		ApiRootPath:      "/",
+		DisableSSLVerify: true,
+	}
+
+	f, err := LoadTargetsFile(base, "test_fixtures/targets.yaml")
+	require.NoError(err)
+	targets := f.Targets
+	require.Len(targets, 5)
+	require.Len(f.Errors, 3, "missing-key, unknown-app and the duplicate name should be skipped")
+
+	sonarr := targets[0]
+	require.Equal("sonarr-main", sonarr.Name)
+	require.Equal("sonarr", sonarr.App)
+	require.Equal("v3", sonarr.ApiVersion)
+	require.Equal("http://sonarr:8989/api/v3", sonarr.BaseURL())
+	require.True(sonarr.EnableAdditionalMetrics)
+	require.True(sonarr.DisableSSLVerify, "base config values should be inherited")
+
+	radarr := targets[1]
+	require.Equal("http://radarr:7878", radarr.Name, "name should default to the url")
+	require.True(radarr.UseFormAuth())
+	require.Equal("user", radarr.AuthUsername)
+
+	prowlarr := targets[2]
+	require.Equal("v1", prowlarr.ApiVersion)
+	require.True(prowlarr.Prowlarr.Backfill)
+	require.Equal(time.Date(2023, 3, 1, 0, 0, 0, 0, time.UTC), prowlarr.Prowlarr.BackfillSinceTime)
+
+	bazarr := targets[3]
+	require.Equal(50, bazarr.Bazarr.SeriesBatchSize)
+	require.Equal(10, bazarr.Bazarr.SeriesBatchConcurrency, "unset bazarr options should keep their defaults")
+
+	sabnzbd := targets[4]
+	require.Equal("https://sabnzbd:9090/sabnzbd", sabnzbd.URL, "the url should be read from sabnzbd.ini")
+	require.Equal("abcdef0123456789abcdef0123456789", sabnzbd.ApiKey)
+}
+
+func TestLoadTargets_MissingFile(t *testing.T) {
+	_, err := LoadTargetsFile(base_config.Config{}, "test_fixtures/does_not_exist.yaml")
+	require.Error(t, err)
+}
+
+func TestProbeTarget(t *testing.T) {
+	require := require.New(t)
+	f, err := LoadTargetsFile(base_config.Config{ApiRootPath: "/"}, "test_fixtures/targets.yaml")
+	require.NoError(err)
+	require.ElementsMatch([]string{"default", "sonarr-form-auth", "secret-file", "unix-socket"}, f.Modules())
+
+	target, err := f.ProbeTarget("sonarr", "http://sonarr:8989", "")
+	require.NoError(err)
+	require.Equal("http://sonarr:8989", target.Name)
+	require.Equal("http://sonarr:8989/api/v3", target.BaseURL())
+	require.Equal("abcdef0123456789abcdef0123456789", target.ApiKey)
+	require.False(target.UseFormAuth())
+
+	target, err = f.ProbeTarget("radarr", "http://radarr:7878", "sonarr-form-auth")
+	require.NoError(err)
+	require.True(target.UseFormAuth())
+	require.Equal("user", target.AuthUsername)
+
+	target, err = f.ProbeTarget("sonarr", "http://sonarr:8989", "secret-file")
+	require.NoError(err)
+	require.Equal("file-password", target.AuthPassword)
+
+	// Probing a second target must not leak settings from the first one.
+	target, err = f.ProbeTarget("lidarr", "http://lidarr:8686", "default")
+	require.NoError(err)
+	require.Equal("lidarr", target.App)
+	require.Equal("v1", target.ApiVersion)
+
+	_, err = f.ProbeTarget("sonarr", "http://sonarr:8989", "unknown")
+	require.Error(err)
+
+	_, err = f.ProbeTarget("plex", "http://plex:32400", "default")
+	require.Error(err)
+}
+
+func TestProbeTarget_CachesSecrets(t *testing.T) {
+	require := require.New(t)
+	var requests atomic.Int32
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests.Add(1)
+		w.Write([]byte(`{"data": {"api-key": "abcdef0123456789abcdef0123456789"}}`))
+	}))
+	defer ts.Close()
+
+	path := filepath.Join(t.TempDir(), "targets.yaml")
+	require.NoError(os.WriteFile(path, []byte(`modules:
+  default:
+    api-key: vault:kv/arr#api-key
+    allowed-targets: sonarr:8989
+`), 0o600))
+	f, err := LoadTargetsFile(base_config.Config{VaultAddr: ts.URL}, path)
+	require.NoError(err)
+
+	for i := 0; i < 3; i++ {
+		target, err := f.ProbeTarget("sonarr", "http://sonarr:8989", "")
+		require.NoError(err)
+		require.Equal("abcdef0123456789abcdef0123456789", target.ApiKey)
+	}
+	require.Equal(int32(1), requests.Load(), "the module's secret should be resolved once per load")
+
+	f, err = LoadTargetsFile(base_config.Config{VaultAddr: ts.URL}, path)
+	require.NoError(err)
+	_, err = f.ProbeTarget("sonarr", "http://sonarr:8989", "")
+	require.NoError(err)
+	require.Equal(int32(2), requests.Load(), "a reloaded file should resolve its secrets again")
+}
+
+func TestProbeTarget_AllowedTargets(t *testing.T) {
+	f, err := LoadTargetsFile(base_config.Config{ApiRootPath: "/"}, "test_fixtures/targets.yaml")
+	require.NoError(t, err)
+
+	tests := []struct {
+		name   string
+		module string
+		target string
+		valid  bool
+	}{
+		{"host-and-port", "default", "http://sonarr:8989", true},
+		{"host-and-port-https", "default", "https://sonarr:8989", true},
+		{"other-port", "default", "http://sonarr:9999", false},
+		{"host-pattern", "default", "http://sonarr.media.lan:8989", true},
+		{"host-pattern-suffix", "default", "http://sonarr.media.lan.evil.com", false},
+		{"url-prefix", "default", "http://lidarr:8686/", true},
+		{"url-prefix-path", "default", "http://lidarr:8686/lidarr", true},
+		{"url-prefix-other-host", "default", "http://lidarr:8686.evil.com/", false},
+		{"user-info", "default", "http://evil.com@sonarr:8989", false},
+		{"host-without-port", "sonarr-form-auth", "http://radarr:7878", true},
+		{"other-host", "default", "http://evil.com", false},
+		{"unsupported-scheme", "default", "ftp://sonarr:8989", false},
+		{"unix-not-allowed", "default", "unix:///var/run/docker.sock", false},
+		{"unix-allowed", "unix-socket", "unix:///run/sonarr.sock", true},
+		{"unix-other-socket", "unix-socket", "unix:///var/run/docker.sock", false},
+		{"unix-prefix", "unix-socket", "unix:///run/sonarr.sock.d/docker.sock", false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := f.ProbeTarget("sonarr", tt.target, tt.module)
+			if tt.valid {
+				require.NoError(t, err)
+			} else {
+				require.Error(t, err)
+			}
+		})
+	}
+}
+
+func TestProbeTarget_NoAllowedTargets(t *testing.T) {
+	f, err := LoadTargetsFile(base_config.Config{ApiRootPath: "/"}, "test_fixtures/targets_no_allowlist.yaml")
+	require.NoError(t, err)
+	_, err = f.ProbeTarget("sonarr", "http://sonarr:8989", "default")
+	require.ErrorContains(t, err, "allowed-targets")
+}
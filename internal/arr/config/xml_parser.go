@@ -3,14 +3,36 @@ package config
 import (
 	"encoding/xml"
 	"errors"
-	"net/url"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"golang.org/x/exp/maps"
+	"golang.org/x/exp/slices"
 )
 
 type xmlConfig struct {
-	XMLName xml.Name `xml:"Config"`
-	ApiKey  string   `xml:"ApiKey"`
-	Port    string   `xml:"Port"`
-	UrlBase string   `xml:"UrlBase"`
+	XMLName              xml.Name `xml:"Config"`
+	ApiKey               string   `xml:"ApiKey"`
+	Port                 string   `xml:"Port"`
+	SslPort              string   `xml:"SslPort"`
+	EnableSsl            string   `xml:"EnableSsl"`
+	BindAddress          string   `xml:"BindAddress"`
+	UrlBase              string   `xml:"UrlBase"`
+	AuthenticationMethod string   `xml:"AuthenticationMethod"`
+	InstanceName         string   `xml:"InstanceName"`
+	PostgresMainDb       string   `xml:"PostgresMainDb"`
+}
+
+// Apps writing a config.xml, with the port they listen on by default.
+var XMLApps = map[string]string{
+	"sonarr":   "8989",
+	"radarr":   "7878",
+	"lidarr":   "8686",
+	"readarr":  "8787",
+	"prowlarr": "9696",
 }
 
 type XML struct{}
@@ -24,11 +46,17 @@ func (p *XML) Unmarshal(b []byte) (map[string]interface{}, error) {
 	if err := xml.Unmarshal(b, &config); err != nil {
 		return nil, err
 	}
+	enableSsl, _ := strconv.ParseBool(config.EnableSsl)
 
 	ret := map[string]interface{}{
-		"api-key":     config.ApiKey,
-		"url-base":    config.UrlBase,
-		"target-port": config.Port,
+		"api-key":       config.ApiKey,
+		"url-base":      config.UrlBase,
+		"target-port":   config.Port,
+		"ssl-port":      config.SslPort,
+		"enable-ssl":    enableSsl,
+		"bind-address":  config.BindAddress,
+		"auth-method":   config.AuthenticationMethod,
+		"instance-name": config.InstanceName,
 	}
 	return ret, nil
 }
@@ -37,22 +65,41 @@ func (p *XML) Marshal(o map[string]interface{}) ([]byte, error) {
 	return nil, errors.New("not implemented")
 }
 
-func (p *XML) Merge(baseURL string) func(src, dest map[string]interface{}) error {
-	return func(src, dest map[string]interface{}) error {
+// DetectApp infers which app wrote the config.xml at path, from its instance
+// name, the database next to it, the name of its PostgreSQL database or the
+// port it listens on, in that order.
+func DetectApp(path string) (string, error) {
+	b, err := os.ReadFile(path)
+	if err != nil {
+		return "", fmt.Errorf("Couldn't read config %w", err)
+	}
+	var config xmlConfig
+	if err := xml.Unmarshal(b, &config); err != nil {
+		return "", fmt.Errorf("Couldn't parse %s: %w", path, err)
+	}
 
-		if src["api-key"] != nil && src["api-key"].(string) != "" {
-			dest["api-key"] = src["api-key"]
-		}
+	apps := maps.Keys(XMLApps)
+	slices.Sort(apps)
 
-		u, err := url.Parse(baseURL)
-		if err != nil {
-			return err
+	for _, app := range apps {
+		if strings.Contains(strings.ToLower(config.InstanceName), app) {
+			return app, nil
+		}
+	}
+	for _, app := range apps {
+		if _, err := os.Stat(filepath.Join(filepath.Dir(path), app+".db")); err == nil {
+			return app, nil
+		}
+	}
+	for _, app := range apps {
+		if strings.HasPrefix(strings.ToLower(config.PostgresMainDb), app) {
+			return app, nil
+		}
+	}
+	for _, app := range apps {
+		if config.Port == XMLApps[app] {
+			return app, nil
 		}
-
-		// Add or replace target port
-		u.Host = u.Hostname() + ":" + src["target-port"].(string)
-		u = u.JoinPath(src["url-base"].(string))
-		dest["url"] = u.String()
-		return nil
 	}
+	return "", fmt.Errorf("Couldn't tell which app %s belongs to, use the app's command instead of auto", path)
 }
@@ -0,0 +1,65 @@
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestDetectApp(t *testing.T) {
+	parameters := []struct {
+		name     string
+		xml      string
+		db       string
+		expected string
+	}{
+		{
+			name:     "instance-name",
+			xml:      "<Config><InstanceName>Radarr 4K</InstanceName><Port>8989</Port></Config>",
+			expected: "radarr",
+		},
+		{
+			name:     "database",
+			xml:      "<Config><InstanceName>Anime</InstanceName><Port>8080</Port></Config>",
+			db:       "lidarr.db",
+			expected: "lidarr",
+		},
+		{
+			name:     "postgres",
+			xml:      "<Config><PostgresMainDb>readarr-main</PostgresMainDb></Config>",
+			expected: "readarr",
+		},
+		{
+			name:     "port",
+			xml:      "<Config><Port>9696</Port></Config>",
+			expected: "prowlarr",
+		},
+		{
+			name: "unknown",
+			xml:  "<Config><Port>8080</Port></Config>",
+		},
+	}
+	for _, p := range parameters {
+		t.Run(p.name, func(t *testing.T) {
+			require := require.New(t)
+			dir := t.TempDir()
+			path := filepath.Join(dir, "config.xml")
+			require.NoError(os.WriteFile(path, []byte(p.xml), 0o600))
+			if p.db != "" {
+				require.NoError(os.WriteFile(filepath.Join(dir, p.db), nil, 0o600))
+			}
+			app, err := DetectApp(path)
+			if p.expected == "" {
+				require.Error(err)
+				return
+			}
+			require.NoError(err)
+			require.Equal(p.expected, app)
+		})
+	}
+
+	_, err := DetectApp("test_fixtures/missing.xml")
+	require.Error(t, err)
+}
@@ -1,5 +1,7 @@
 package model
 
+import "time"
+
 // RootFolder - Stores struct of JSON response
 type RootFolder []struct {
 	Path      string `json:"path"`
@@ -8,19 +10,31 @@ type RootFolder []struct {
 
 // SystemStatus - Stores struct of JSON response
 type SystemStatus struct {
-	Version string `json:"version"`
-	AppData string `json:"appData"`
-	Branch  string `json:"branch"`
+	AppName          string    `json:"appName"`
+	InstanceName     string    `json:"instanceName"`
+	Version          string    `json:"version"`
+	AppData          string    `json:"appData"`
+	Branch           string    `json:"branch"`
+	OsName           string    `json:"osName"`
+	OsVersion        string    `json:"osVersion"`
+	IsDocker         bool      `json:"isDocker"`
+	RuntimeName      string    `json:"runtimeName"`
+	RuntimeVersion   string    `json:"runtimeVersion"`
+	DatabaseType     string    `json:"databaseType"`
+	DatabaseVersion  string    `json:"databaseVersion"`
+	Authentication   string    `json:"authentication"`
+	MigrationVersion int       `json:"migrationVersion"`
+	StartTime        time.Time `json:"startTime"`
 }
 
-// Queue - Stores struct of JSON response
-type Queue struct {
-	Page          int            `json:"page"`
-	PageSize      int            `json:"pageSize"`
-	SortKey       string         `json:"sortKey"`
-	SortDirection string         `json:"sortDirection"`
-	TotalRecords  int            `json:"totalRecords"`
-	Records       []QueueRecords `json:"records"`
+// Page - Stores struct of a JSON response of paged endpoints like queue and wanted/cutoff
+type Page[T any] struct {
+	Page          int    `json:"page"`
+	PageSize      int    `json:"pageSize"`
+	SortKey       string `json:"sortKey"`
+	SortDirection string `json:"sortDirection"`
+	TotalRecords  int    `json:"totalRecords"`
+	Records       []T    `json:"records"`
 }
 
 // QueueRecords - Stores struct of JSON response
@@ -42,6 +56,14 @@ type History struct {
 	TotalRecords int `json:"totalRecords"`
 }
 
+// HistoryRecord - Stores struct of JSON response of history/since
+type HistoryRecord struct {
+	ID        int               `json:"id"`
+	Date      time.Time         `json:"date"`
+	EventType string            `json:"eventType"`
+	Data      map[string]string `json:"data"`
+}
+
 type SystemHealth []SystemHealthMessage
 
 // SystemHealth - Stores struct of JSON response
@@ -0,0 +1,43 @@
+package model
+
+import "time"
+
+// QualityProfile - Stores struct of JSON response
+type QualityProfile []struct {
+	ID   int    `json:"id"`
+	Name string `json:"name"`
+}
+
+// CutoffUnmet - Stores struct of JSON response of wanted/cutoff. Records are
+// episodes, movies, albums or books depending on the app.
+type CutoffUnmet struct {
+	QualityProfileID int          `json:"qualityProfileId"` // Radarr
+	MovieFile        *WantedFile  `json:"movieFile"`        // Radarr
+	EpisodeFile      *WantedFile  `json:"episodeFile"`      // Sonarr, with includeEpisodeFile
+	Series           *WantedOwner `json:"series"`           // Sonarr, with includeSeries
+	Artist           *WantedOwner `json:"artist"`           // Lidarr, with includeArtist
+	Author           *WantedOwner `json:"author"`           // Readarr, with includeAuthor
+}
+
+// WantedFile - Stores struct of the file of a wanted item
+type WantedFile struct {
+	Quality struct {
+		Quality struct {
+			Name string `json:"name"`
+		} `json:"quality"`
+	} `json:"quality"`
+}
+
+// WantedOwner - Stores struct of the series, artist or author of a wanted item
+type WantedOwner struct {
+	QualityProfileID int `json:"qualityProfileId"`
+}
+
+// MissingItem - Stores struct of JSON response of wanted/missing. Records are
+// episodes, movies, albums or books depending on the app.
+type MissingItem struct {
+	AirDateUtc      time.Time `json:"airDateUtc"`      // Sonarr
+	DigitalRelease  time.Time `json:"digitalRelease"`  // Radarr
+	PhysicalRelease time.Time `json:"physicalRelease"` // Radarr
+	ReleaseDate     time.Time `json:"releaseDate"`     // Lidarr, Readarr
+}
@@ -0,0 +1,227 @@
+package client
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+)
+
+// OAUTH2_DEFAULT_EXPIRY is how long a token is used when the token endpoint
+// doesn't say when it expires.
+var OAUTH2_DEFAULT_EXPIRY = 5 * time.Minute
+
+// OAUTH2_EXPIRY_MARGIN is how long before it expires a token is refreshed, so
+// it doesn't expire in flight.
+var OAUTH2_EXPIRY_MARGIN = 30 * time.Second
+
+type chain []Authenticator
+
+// Chain returns an Authenticator applying each of auths in order, skipping
+// nil ones, e.g. an app's API key and the credentials of a proxy in front of it.
+func Chain(auths ...Authenticator) Authenticator {
+	var ret chain
+	for _, a := range auths {
+		if a != nil {
+			ret = append(ret, a)
+		}
+	}
+	switch len(ret) {
+	case 0:
+		return nil
+	case 1:
+		return ret[0]
+	default:
+		return ret
+	}
+}
+
+func (c chain) Auth(req *http.Request) error {
+	for _, a := range c {
+		if err := a.Auth(req); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Invalidate drops the sessions of the Reauthenticators in the chain.
+func (c chain) Invalidate(req *http.Request) {
+	for _, a := range c {
+		if ra, ok := a.(Reauthenticator); ok {
+			ra.Invalidate(req)
+		}
+	}
+}
+
+func (c chain) CloseIdleConnections() {
+	for _, a := range c {
+		closeIdleConnections(a)
+	}
+}
+
+// reauthenticator returns auth as a Reauthenticator when it, or an
+// Authenticator in its chain, holds a session.
+func reauthenticator(auth Authenticator) (Reauthenticator, bool) {
+	if c, ok := auth.(chain); ok {
+		for _, a := range c {
+			if _, ok := a.(Reauthenticator); ok {
+				return c, true
+			}
+		}
+		return nil, false
+	}
+	ra, ok := auth.(Reauthenticator)
+	return ra, ok
+}
+
+// ProxyAuth returns the Authenticator adding headers and an OAuth2 token for a
+// proxy in front of the app, nil when neither is configured. The token is
+// fetched with the proxy and TLS settings of opts, the app's transport.
+func ProxyAuth(headers map[string]string, oauth2 OAuth2Config, opts TransportOptions) Authenticator {
+	var auths []Authenticator
+	if len(headers) > 0 {
+		auths = append(auths, &HeaderAuth{Headers: headers})
+	}
+	if oauth2.TokenURL != "" {
+		// The token endpoint is reached over the network even when the app is on a unix socket
+		opts.Socket = ""
+		auths = append(auths, &OAuth2Auth{
+			OAuth2Config: oauth2,
+			Transport:    BaseTransport(opts),
+		})
+	}
+	return Chain(auths...)
+}
+
+type authTransport struct {
+	inner http.RoundTripper
+	auth  Authenticator
+}
+
+// AuthTransport returns a RoundTripper authenticating requests with auth before
+// sending them with inner, e.g. for logins which must pass the app's proxy too.
+func AuthTransport(inner http.RoundTripper, auth Authenticator) http.RoundTripper {
+	if auth == nil {
+		return inner
+	}
+	return &authTransport{inner: inner, auth: auth}
+}
+
+func (t *authTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	req = req.Clone(req.Context())
+	if err := t.auth.Auth(req); err != nil {
+		return nil, fmt.Errorf("Error authenticating request: %w", err)
+	}
+	return t.inner.RoundTrip(req)
+}
+
+func (t *authTransport) CloseIdleConnections() {
+	closeIdleConnections(t.inner)
+	closeIdleConnections(t.auth)
+}
+
+// HeaderAuth sets static headers on requests, e.g. the service token of an
+// SSO proxy like Cloudflare Access.
+type HeaderAuth struct {
+	Headers map[string]string
+}
+
+func (a *HeaderAuth) Auth(req *http.Request) error {
+	for name, val := range a.Headers {
+		req.Header.Set(name, val)
+	}
+	return nil
+}
+
+// OAuth2Config is a client of the OAuth2 client credentials grant.
+type OAuth2Config struct {
+	TokenURL     string
+	ClientID     string
+	ClientSecret string
+	Scopes       []string
+}
+
+// OAuth2Auth sets a bearer token fetched from TokenURL with the OAuth2 client
+// credentials grant, e.g. for Authentik or Authelia. The token is shared by all
+// requests and fetched again shortly before it expires.
+type OAuth2Auth struct {
+	OAuth2Config
+	Transport http.RoundTripper
+	token     string
+	expiry    time.Time
+	mutex     sync.Mutex
+}
+
+func (a *OAuth2Auth) Auth(req *http.Request) error {
+	a.mutex.Lock()
+	defer a.mutex.Unlock()
+	if a.token == "" || time.Now().Add(OAUTH2_EXPIRY_MARGIN).After(a.expiry) {
+		if err := a.fetchToken(req); err != nil {
+			return fmt.Errorf("Failed to fetch OAuth2 token: %w", err)
+		}
+	}
+	req.Header.Set("Authorization", "Bearer "+a.token)
+	return nil
+}
+
+// Invalidate drops the token req was sent with, e.g. when the proxy revoked it.
+func (a *OAuth2Auth) Invalidate(req *http.Request) {
+	a.mutex.Lock()
+	defer a.mutex.Unlock()
+	if a.token != "" && req.Header.Get("Authorization") == "Bearer "+a.token {
+		a.token = ""
+	}
+}
+
+func (a *OAuth2Auth) CloseIdleConnections() {
+	closeIdleConnections(a.Transport)
+}
+
+func (a *OAuth2Auth) fetchToken(req *http.Request) error {
+	form := url.Values{"grant_type": {"client_credentials"}}
+	if len(a.Scopes) > 0 {
+		form.Set("scope", strings.Join(a.Scopes, " "))
+	}
+	tokenReq, err := http.NewRequestWithContext(req.Context(), "POST", a.TokenURL, strings.NewReader(form.Encode()))
+	if err != nil {
+		return err
+	}
+	tokenReq.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	tokenReq.Header.Set("Accept", "application/json")
+	tokenReq.SetBasicAuth(url.QueryEscape(a.ClientID), url.QueryEscape(a.ClientSecret))
+
+	client := &http.Client{Transport: a.Transport}
+	resp, err := client.Do(tokenReq)
+	if err != nil {
+		return redactError(err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("Received Status Code %d", resp.StatusCode)
+	}
+
+	var body struct {
+		AccessToken string `json:"access_token"`
+		TokenType   string `json:"token_type"`
+		ExpiresIn   int64  `json:"expires_in"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&body); err != nil {
+		return fmt.Errorf("Couldn't parse token response: %w", err)
+	}
+	if body.AccessToken == "" {
+		return fmt.Errorf("Token response has no access_token")
+	}
+	if body.TokenType != "" && !strings.EqualFold(body.TokenType, "bearer") {
+		return fmt.Errorf("Unsupported token type %s", body.TokenType)
+	}
+	a.token = body.AccessToken
+	a.expiry = time.Now().Add(OAUTH2_DEFAULT_EXPIRY)
+	if body.ExpiresIn > 0 {
+		a.expiry = time.Now().Add(time.Duration(body.ExpiresIn) * time.Second)
+	}
+	return nil
+}
@@ -0,0 +1,132 @@
+package client
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestChain(t *testing.T) {
+	require := require.New(t)
+	require.Nil(Chain(nil, nil), "An empty chain should not authenticate")
+
+	headers := &HeaderAuth{Headers: map[string]string{"X-Test": "1"}}
+	require.Same(headers, Chain(nil, headers), "A single Authenticator should be used as is")
+
+	req, err := http.NewRequest("GET", "http://localhost", nil)
+	require.NoError(err)
+	auth := Chain(headers, &HeaderAuth{Headers: map[string]string{"CF-Access-Client-Id": "id", "X-Test": "2"}})
+	require.NoError(auth.Auth(req))
+	require.Equal("2", req.Header.Get("X-Test"), "Later Authenticators should override earlier ones")
+	require.Equal("id", req.Header.Get("Cf-Access-Client-Id"))
+}
+
+func TestOAuth2Auth(t *testing.T) {
+	require := require.New(t)
+	var fetches atomic.Int32
+	var expiresIn atomic.Int64
+	expiresIn.Store(3600)
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fetches.Add(1)
+		id, secret, ok := r.BasicAuth()
+		if !ok || id != "client" || secret != "secret" {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		require.NoError(r.ParseForm())
+		require.Equal("client_credentials", r.PostForm.Get("grant_type"))
+		require.Equal("read write", r.PostForm.Get("scope"))
+		w.Header().Set("Content-Type", "application/json")
+		w.Write([]byte(`{"access_token": "token", "token_type": "Bearer", "expires_in": ` + strconv.FormatInt(expiresIn.Load(), 10) + `}`))
+	}))
+	defer ts.Close()
+
+	auth := ProxyAuth(nil, OAuth2Config{
+		TokenURL:     ts.URL,
+		ClientID:     "client",
+		ClientSecret: "secret",
+		Scopes:       []string{"read", "write"},
+	}, TransportOptions{})
+	for i := 0; i < 3; i++ {
+		req, err := http.NewRequest("GET", "http://localhost", nil)
+		require.NoError(err)
+		require.NoError(auth.Auth(req))
+		require.Equal("Bearer token", req.Header.Get("Authorization"))
+	}
+	require.Equal(int32(1), fetches.Load(), "The token should be reused until it expires")
+
+	// Tokens expiring within the margin are fetched again
+	expiresIn.Store(10)
+	auth.(*OAuth2Auth).expiry = time.Now()
+	for i := 0; i < 2; i++ {
+		req, err := http.NewRequest("GET", "http://localhost", nil)
+		require.NoError(err)
+		require.NoError(auth.Auth(req))
+	}
+	require.Equal(int32(3), fetches.Load())
+
+	auth = ProxyAuth(nil, OAuth2Config{TokenURL: ts.URL, ClientID: "client", ClientSecret: "wrong"}, TransportOptions{})
+	req, err := http.NewRequestWithContext(context.Background(), "GET", "http://localhost", nil)
+	require.NoError(err)
+	err = auth.Auth(req)
+	require.Error(err)
+	require.NotContains(err.Error(), "wrong")
+}
+
+func TestProxyAuth_OAuth2TransportOptions(t *testing.T) {
+	require := require.New(t)
+	ts := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		w.Write([]byte(`{"access_token": "token", "token_type": "Bearer", "expires_in": 3600}`))
+	}))
+	defer ts.Close()
+
+	roots := x509.NewCertPool()
+	roots.AddCert(ts.Certificate())
+	oauth2 := OAuth2Config{TokenURL: ts.URL, ClientID: "client", ClientSecret: "secret"}
+
+	req, err := http.NewRequest("GET", "http://localhost", nil)
+	require.NoError(err)
+	require.Error(ProxyAuth(nil, oauth2, TransportOptions{}).Auth(req),
+		"the token endpoint's certificate shouldn't be trusted without the app's TLS settings")
+
+	auth := ProxyAuth(nil, oauth2, TransportOptions{
+		TLS:    &tls.Config{RootCAs: roots},
+		Socket: "/nonexistent.sock",
+	})
+	req, err = http.NewRequest("GET", "http://localhost", nil)
+	require.NoError(err)
+	require.NoError(auth.Auth(req), "the token should be fetched with the app's TLS settings, but not its socket")
+	require.Equal("Bearer token", req.Header.Get("Authorization"))
+}
+
+func TestAuthTransport(t *testing.T) {
+	require := require.New(t)
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(r.Header.Get("X-Test")))
+	}))
+	defer ts.Close()
+
+	inner := BaseTransport(TransportOptions{})
+	require.Same(inner, AuthTransport(inner, nil))
+
+	client := &http.Client{Transport: AuthTransport(inner, &HeaderAuth{Headers: map[string]string{"X-Test": "1"}})}
+	req, err := http.NewRequest("POST", ts.URL, nil)
+	require.NoError(err)
+	resp, err := client.Do(req)
+	require.NoError(err)
+	defer resp.Body.Close()
+	body, err := io.ReadAll(resp.Body)
+	require.NoError(err)
+	require.Equal("1", string(body))
+	require.Empty(req.Header.Get("X-Test"), "The caller's request should not be modified")
+}
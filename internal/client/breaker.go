@@ -0,0 +1,103 @@
+package client
+
+import (
+	"fmt"
+	"net/url"
+	"sync"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+var breakerOpen = prometheus.NewDesc(
+	"exportarr_upstream_circuit_breaker_open",
+	"Whether the circuit breaker of the upstream app is open, stopping requests to it.",
+	[]string{"url"},
+	nil,
+)
+
+// breaker stops requests to an app for a cool-down period after repeated failures.
+// Once the cool-down is over requests are let through again; the first failure
+// reopens the breaker, the first success closes it.
+type breaker struct {
+	url       string
+	mutex     sync.Mutex
+	failures  int
+	openUntil time.Time
+}
+
+var (
+	breakers      = map[string]*breaker{}
+	breakersMutex sync.Mutex
+)
+
+// breakerFor returns the breaker shared by every request to the app at key.
+func breakerFor(key string) *breaker {
+	breakersMutex.Lock()
+	defer breakersMutex.Unlock()
+	b, ok := breakers[key]
+	if !ok {
+		b = &breaker{url: key}
+		breakers[key] = b
+	}
+	return b
+}
+
+// breakerKey identifies the app a request to u is sent to.
+func (t *ExportarrTransport) breakerKey(u *url.URL) string {
+	if t.socket != "" {
+		return "unix://" + t.socket
+	}
+	return u.Scheme + "://" + u.Host
+}
+
+// allow returns an error while the breaker is open.
+func (b *breaker) allow() error {
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+	if b.open() {
+		return fmt.Errorf("Circuit breaker for %s is open until %s", b.url, b.openUntil.Format(time.RFC3339))
+	}
+	return nil
+}
+
+// record updates the breaker with the outcome of a request.
+func (b *breaker) record(success bool, p RetryPolicy) {
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+	if success {
+		b.failures = 0
+		b.openUntil = time.Time{}
+		return
+	}
+	b.failures++
+	if b.failures >= p.BreakerThreshold {
+		b.openUntil = time.Now().Add(p.BreakerCooldown)
+	}
+}
+
+// open reports whether the breaker is open. The mutex must be held.
+func (b *breaker) open() bool {
+	return time.Now().Before(b.openUntil)
+}
+
+// breakerCollector reports the state of every breaker.
+type breakerCollector struct{}
+
+func (breakerCollector) Describe(ch chan<- *prometheus.Desc) {
+	ch <- breakerOpen
+}
+
+func (breakerCollector) Collect(ch chan<- prometheus.Metric) {
+	breakersMutex.Lock()
+	defer breakersMutex.Unlock()
+	for _, b := range breakers {
+		b.mutex.Lock()
+		open := 0.0
+		if b.open() {
+			open = 1
+		}
+		b.mutex.Unlock()
+		ch <- prometheus.MustNewConstMetric(breakerOpen, prometheus.GaugeValue, open, b.url)
+	}
+}
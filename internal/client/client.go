@@ -1,46 +1,66 @@
 package client
 
 import (
+	"context"
 	"crypto/tls"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"io"
+	"net"
 	"net/http"
 	"net/url"
 	"strings"
+	"time"
 
 	"go.uber.org/zap"
 )
 
 // Client struct is an *Arr client.
 type Client struct {
-	httpClient http.Client
-	URL            url.URL
-	APIRootPath    string
+	httpClient  http.Client
+	URL         url.URL
+	APIRootPath string
+	App         string // App requests are instrumented with, e.g. "sonarr"
 }
 
 type QueryParams = url.Values
 
-// NewClient method initializes a new *Arr client.
-func NewClient(baseURL string, insecureSkipVerify bool, auth Authenticator, apiRoot string) (*Client, error) {
+// NewClient method initializes a new *Arr client. baseURL may be a unix:// URL
+// of the app's socket, see SocketURL.
+func NewClient(baseURL string, opts TransportOptions, auth Authenticator, apiRoot string) (*Client, error) {
 
-	u, err := url.Parse(baseURL)
+	u, socket, err := SocketURL(baseURL)
 	if err != nil {
 		return nil, fmt.Errorf("Failed to parse URL(%s): %w", baseURL, err)
 	}
+	if socket != "" {
+		opts.Socket = socket
+	}
+	transport := NewExportarrTransport(BaseTransport(opts), auth)
+	transport.socket = opts.Socket
+	if opts.Retry != nil {
+		transport.policy = *opts.Retry
+	}
 
 	return &Client{
 		httpClient: http.Client{
 			CheckRedirect: func(req *http.Request, via []*http.Request) error {
 				return http.ErrUseLastResponse
 			},
-			Transport: NewExportarrTransport(BaseTransport(insecureSkipVerify), auth),
+			Transport: transport,
 		},
-		URL: *u,
+		URL:         *u,
 		APIRootPath: apiRoot,
 	}, nil
 }
 
+// CloseIdleConnections closes the idle connections of the client, including
+// those of its authentication, e.g. logins and OAuth2 token requests.
+func (c *Client) CloseIdleConnections() {
+	c.httpClient.CloseIdleConnections()
+}
+
 func (c *Client) unmarshalBody(b io.Reader, target interface{}) (err error) {
 	defer func() {
 		if r := recover(); r != nil {
@@ -65,8 +85,9 @@ func (c *Client) unmarshalBody(b io.Reader, target interface{}) (err error) {
 	return
 }
 
-// DoRequest - Take a HTTP Request and return Unmarshaled data
-func (c *Client) DoRequest(endpoint string, target interface{}, queryParams ...QueryParams) error {
+// DoRequest - Take a HTTP Request and return Unmarshaled data.
+// The request is cancelled when ctx is done.
+func (c *Client) DoRequest(ctx context.Context, endpoint string, target interface{}, queryParams ...QueryParams) error {
 	values := c.URL.Query()
 
 	// merge all query params
@@ -80,24 +101,83 @@ func (c *Client) DoRequest(endpoint string, target interface{}, queryParams ...Q
 	url := c.URL.JoinPath(c.APIRootPath, endpoint)
 	url.RawQuery = values.Encode()
 	zap.S().Infow("Sending HTTP request",
-		"url", url)
+		"url", url.Redacted())
 
-	req, err := http.NewRequest("GET", url.String(), nil)
+	ctx = withRequestLabels(ctx, c.App, endpoint)
+	req, err := http.NewRequestWithContext(ctx, "GET", url.String(), nil)
 	if err != nil {
-		return fmt.Errorf("Failed to create HTTP Request(%s): %w", url, err)
+		return fmt.Errorf("Failed to create HTTP Request(%s): %w", url.Redacted(), err)
 	}
 	resp, err := c.httpClient.Do(req)
 	if err != nil {
-		return fmt.Errorf("Failed to execute HTTP Request(%s): %w", url, err)
+		return fmt.Errorf("Failed to execute HTTP Request(%s): %w", url.Redacted(), redactError(err))
 	}
 	defer resp.Body.Close()
 	return c.unmarshalBody(resp.Body, target)
 }
 
-func BaseTransport(insecureSkipVerify bool) http.RoundTripper {
-	baseTransport := http.DefaultTransport
-	if insecureSkipVerify {
-		baseTransport.(*http.Transport).TLSClientConfig = &tls.Config{InsecureSkipVerify: true}
+// sensitiveParams are query parameters holding credentials, e.g. the apikey
+// SABnzbd's authenticator adds to each request.
+var sensitiveParams = []string{"apikey", "api_key", "access_token", "token"}
+
+// redactError removes credentials from the URL of err, as the http.Client
+// reports the request's URL, as sent, in its errors.
+func redactError(err error) error {
+	var urlErr *url.Error
+	if errors.As(err, &urlErr) {
+		urlErr.URL = redactURL(urlErr.URL)
+	}
+	return err
+}
+
+func redactURL(s string) string {
+	u, err := url.Parse(s)
+	if err != nil {
+		return "<unparsable url>"
+	}
+	values := u.Query()
+	for key := range values {
+		for _, param := range sensitiveParams {
+			if strings.EqualFold(key, param) {
+				values.Set(key, "REDACTED")
+			}
+		}
+	}
+	u.RawQuery = values.Encode()
+	return u.Redacted()
+}
+
+// TransportOptions configure how a client connects to the app.
+type TransportOptions struct {
+	TLS    *tls.Config  // nil to use the defaults
+	Proxy  *url.URL     // HTTP(S) or SOCKS5 proxy, nil to use HTTP_PROXY, HTTPS_PROXY and NO_PROXY
+	Socket string       // Path of a Unix socket to connect to instead of the URL's host
+	Retry  *RetryPolicy // nil to use DefaultRetryPolicy
+}
+
+// BaseTransport returns a transport of its own for a client, so its settings
+// never leak into http.DefaultTransport. Connections are kept alive between
+// scrapes and HTTP/2 is used when the app supports it.
+func BaseTransport(opts TransportOptions) http.RoundTripper {
+	baseTransport := http.DefaultTransport.(*http.Transport).Clone()
+	baseTransport.ForceAttemptHTTP2 = true
+	baseTransport.MaxIdleConns = 100
+	baseTransport.MaxIdleConnsPerHost = 16
+	baseTransport.MaxConnsPerHost = 32
+	baseTransport.IdleConnTimeout = 5 * time.Minute
+	if opts.TLS != nil {
+		baseTransport.TLSClientConfig = opts.TLS.Clone()
+	}
+	if opts.Proxy != nil {
+		baseTransport.Proxy = http.ProxyURL(opts.Proxy)
+	}
+	if opts.Socket != "" {
+		// Every request goes to the socket, whatever its URL's host, so no proxy applies
+		baseTransport.Proxy = nil
+		dialer := &net.Dialer{Timeout: 30 * time.Second}
+		baseTransport.DialContext = func(ctx context.Context, _, _ string) (net.Conn, error) {
+			return dialer.DialContext(ctx, "unix", opts.Socket)
+		}
 	}
 	return baseTransport
 }
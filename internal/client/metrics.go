@@ -0,0 +1,83 @@
+package client
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"strings"
+
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+var (
+	requestDuration = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Name:    "exportarr_upstream_request_duration_seconds",
+		Help:    "Latency of each attempt at a request to the upstream app.",
+		Buckets: []float64{.01, .05, .1, .25, .5, 1, 2.5, 5, 10, 30},
+	}, []string{"app", "endpoint", "status_class"})
+	requestsTotal = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Name: "exportarr_upstream_requests_total",
+		Help: "Total number of requests made to the upstream app, by the status class of the final attempt.",
+	}, []string{"app", "endpoint", "status_class"})
+	retriesTotal = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Name: "exportarr_upstream_retries_total",
+		Help: "Total number of retried requests to the upstream app.",
+	}, []string{"app", "endpoint"})
+	failuresTotal = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Name: "exportarr_upstream_request_failures_total",
+		Help: "Total number of requests to the upstream app which failed after all retries.",
+	}, []string{"app", "endpoint"})
+	loginsTotal = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Name: "exportarr_upstream_logins_total",
+		Help: "Total number of logins to the upstream app, by the reason a new session was needed.",
+	}, []string{"app", "reason"})
+	loginFailuresTotal = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Name: "exportarr_upstream_login_failures_total",
+		Help: "Total number of failed logins to the upstream app.",
+	}, []string{"app"})
+)
+
+// Metrics returns the collectors instrumenting requests to upstream apps,
+// to be registered with the exporter's own metrics.
+func Metrics() []prometheus.Collector {
+	return []prometheus.Collector{requestDuration, requestsTotal, retriesTotal, failuresTotal,
+		loginsTotal, loginFailuresTotal, breakerCollector{}}
+}
+
+// ObserveLogin counts a login to app for reason, e.g. "expiring", and its failure when err is set.
+func ObserveLogin(app string, reason string, err error) {
+	loginsTotal.WithLabelValues(app, reason).Inc()
+	if err != nil {
+		loginFailuresTotal.WithLabelValues(app).Inc()
+	}
+}
+
+type requestLabelsKey struct{}
+
+type requestLabels struct {
+	app      string
+	endpoint string
+}
+
+// withRequestLabels attaches the app and endpoint a request is instrumented with.
+func withRequestLabels(ctx context.Context, app string, endpoint string) context.Context {
+	return context.WithValue(ctx, requestLabelsKey{}, requestLabels{
+		app:      app,
+		endpoint: strings.Trim(endpoint, "/"),
+	})
+}
+
+func labelsFor(req *http.Request) requestLabels {
+	if l, ok := req.Context().Value(requestLabelsKey{}).(requestLabels); ok {
+		return l
+	}
+	return requestLabels{endpoint: strings.Trim(req.URL.Path, "/")}
+}
+
+// statusClass returns the class of a response, e.g. "2xx", or "error" when no response was received.
+func statusClass(resp *http.Response, err error) string {
+	if err != nil || resp == nil {
+		return "error"
+	}
+	return fmt.Sprintf("%dxx", resp.StatusCode/100)
+}
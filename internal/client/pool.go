@@ -0,0 +1,66 @@
+package client
+
+import "sync"
+
+// Pool holds the long-lived clients of the targets of a config, so the
+// collectors of a target share its connections and sessions instead of
+// building a client on every scrape. Clients are keyed by the identity of
+// their target, e.g. its config, rather than by its settings or credentials.
+// The pool is closed once the config is replaced.
+type Pool struct {
+	mutex   sync.Mutex
+	clients map[any]*Client
+	closed  bool
+}
+
+func NewPool() *Pool {
+	return &Pool{clients: map[any]*Client{}}
+}
+
+// Get returns the client of the target key, building it with build on first
+// use. A nil or closed pool doesn't keep the clients it builds, e.g. for
+// /probe requests or scrapes still in flight after a reload.
+func (p *Pool) Get(key any, build func() (*Client, error)) (*Client, error) {
+	if p == nil {
+		return build()
+	}
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+	if c, ok := p.clients[key]; ok {
+		return c, nil
+	}
+	c, err := build()
+	if err != nil || p.closed {
+		return c, err
+	}
+	p.clients[key] = c
+	return c, nil
+}
+
+// Close drops the clients of the pool, closing their idle connections.
+// Requests in flight complete, and their connections are closed once idle.
+func (p *Pool) Close() {
+	if p == nil {
+		return
+	}
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+	for key, c := range p.clients {
+		c.CloseIdleConnections()
+		delete(p.clients, key)
+	}
+	p.closed = true
+}
+
+// idleCloser is implemented by the transports and authenticators holding
+// connections, like http.Transport.
+type idleCloser interface {
+	CloseIdleConnections()
+}
+
+// closeIdleConnections closes the idle connections of v if it holds any.
+func closeIdleConnections(v any) {
+	if c, ok := v.(idleCloser); ok {
+		c.CloseIdleConnections()
+	}
+}
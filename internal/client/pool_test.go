@@ -0,0 +1,73 @@
+package client
+
+import (
+	"context"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestPool(t *testing.T) {
+	require := require.New(t)
+	var builds int
+	build := func() (*Client, error) {
+		builds++
+		return NewClient("http://localhost", TransportOptions{}, nil, "")
+	}
+
+	p := NewPool()
+	first, err := p.Get("sonarr", build)
+	require.NoError(err)
+	second, err := p.Get("sonarr", build)
+	require.NoError(err)
+	require.Same(first, second, "a target should keep its client")
+	other, err := p.Get("radarr", build)
+	require.NoError(err)
+	require.NotSame(first, other, "each target should have its own client")
+	require.Equal(2, builds)
+
+	p.Close()
+	afterClose, err := p.Get("sonarr", build)
+	require.NoError(err)
+	require.NotSame(first, afterClose)
+	again, err := p.Get("sonarr", build)
+	require.NoError(err)
+	require.NotSame(afterClose, again, "a closed pool shouldn't keep clients")
+
+	var nilPool *Pool
+	uncached, err := nilPool.Get("sonarr", build)
+	require.NoError(err)
+	require.NotSame(uncached, again)
+	nilPool.Close()
+}
+
+func TestPool_CloseIdleConnections(t *testing.T) {
+	require := require.New(t)
+	var closed atomic.Int32
+	ts := httptest.NewUnstartedServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("{}")) //nolint:errcheck
+	}))
+	ts.Config.ConnState = func(_ net.Conn, state http.ConnState) {
+		if state == http.StateClosed {
+			closed.Add(1)
+		}
+	}
+	ts.Start()
+	defer ts.Close()
+
+	p := NewPool()
+	c, err := p.Get(ts.URL, func() (*Client, error) {
+		return NewClient(ts.URL, TransportOptions{}, nil, "")
+	})
+	require.NoError(err)
+	require.NoError(c.DoRequest(context.Background(), "test", &map[string]any{}))
+	require.Equal(int32(0), closed.Load(), "the connection should be kept alive")
+
+	p.Close()
+	require.Eventually(func() bool { return closed.Load() == 1 }, time.Second, 10*time.Millisecond)
+}
@@ -0,0 +1,98 @@
+package client
+
+import (
+	"context"
+	"errors"
+	"math/rand"
+	"net/http"
+	"strconv"
+	"time"
+)
+
+// RetryPolicy controls how requests to an app are retried when they fail.
+type RetryPolicy struct {
+	Attempts         int           // Attempts for each request, including the first; 0 or 1 disables retries
+	Backoff          time.Duration // Delay before the first retry, doubled for each further retry
+	MaxBackoff       time.Duration // Upper bound of the delay between attempts
+	BreakerThreshold int           // Consecutive failed requests which open the circuit breaker, 0 to disable it
+	BreakerCooldown  time.Duration // How long an open circuit breaker stops requests to the app
+}
+
+// DefaultRetryPolicy is used by transports created with NewExportarrTransport
+// and by clients whose TransportOptions don't set one.
+var DefaultRetryPolicy = RetryPolicy{
+	Attempts:         3,
+	Backoff:          250 * time.Millisecond,
+	MaxBackoff:       5 * time.Second,
+	BreakerThreshold: 0,
+	BreakerCooldown:  time.Minute,
+}
+
+// retryable reports whether an attempt failed in a way that is worth retrying:
+// the app couldn't be reached, it returned a server error or it asked us to slow down.
+func retryable(resp *http.Response, err error) bool {
+	if err != nil {
+		return !errors.Is(err, context.Canceled) && !errors.Is(err, context.DeadlineExceeded)
+	}
+	return resp.StatusCode >= 500 || resp.StatusCode == http.StatusTooManyRequests
+}
+
+// delay returns how long to wait before the given retry, starting at 1. The
+// Retry-After header of resp is honored when set, otherwise the delay backs off
+// exponentially with jitter. ok is false when the app asked for a longer delay
+// than MaxBackoff, in which case the request shouldn't be retried.
+func (p RetryPolicy) delay(retry int, resp *http.Response) (d time.Duration, ok bool) {
+	if after, found := retryAfter(resp); found {
+		return after, after <= p.MaxBackoff
+	}
+	d = p.Backoff
+	for i := 1; i < retry && d < p.MaxBackoff; i++ {
+		d *= 2
+	}
+	if d > p.MaxBackoff {
+		d = p.MaxBackoff
+	}
+	if d <= 0 {
+		return 0, true
+	}
+	// Equal jitter: wait between half and all of the backoff.
+	half := d / 2
+	return half + time.Duration(rand.Int63n(int64(d-half)+1)), true
+}
+
+// retryAfter parses the Retry-After header of resp, given in seconds or as a date.
+func retryAfter(resp *http.Response) (time.Duration, bool) {
+	if resp == nil {
+		return 0, false
+	}
+	val := resp.Header.Get("Retry-After")
+	if val == "" {
+		return 0, false
+	}
+	if seconds, err := strconv.Atoi(val); err == nil && seconds >= 0 {
+		return time.Duration(seconds) * time.Second, true
+	}
+	if date, err := http.ParseTime(val); err == nil {
+		d := time.Until(date)
+		if d < 0 {
+			d = 0
+		}
+		return d, true
+	}
+	return 0, false
+}
+
+// sleep waits for d, returning early with the context's error when it is done first.
+func sleep(ctx context.Context, d time.Duration) error {
+	if d <= 0 {
+		return ctx.Err()
+	}
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-timer.C:
+		return nil
+	}
+}
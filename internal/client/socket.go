@@ -0,0 +1,40 @@
+package client
+
+import (
+	"fmt"
+	"net/url"
+	"strings"
+)
+
+// SocketURL parses rawURL, splitting a unix:// URL into the HTTP URL requests
+// are made to and the path of the Unix socket they are sent over. The socket
+// is the path up to the first element ending in .sock, the rest is the HTTP
+// path, e.g. unix:///run/sonarr.sock/api/v3 is /api/v3 on /run/sonarr.sock.
+// Other URLs are returned as they are, with an empty socket.
+func SocketURL(rawURL string) (*url.URL, string, error) {
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return nil, "", err
+	}
+	if u.Scheme != "unix" {
+		return u, "", nil
+	}
+	if u.Host != "" || !strings.HasPrefix(u.Path, "/") {
+		return nil, "", fmt.Errorf("unix:// URLs need an absolute socket path, e.g. unix:///run/sonarr.sock")
+	}
+
+	elems := strings.SplitAfter(u.Path, "/")
+	for i, elem := range elems {
+		if !strings.HasSuffix(strings.TrimSuffix(elem, "/"), ".sock") {
+			continue
+		}
+		socket := strings.TrimSuffix(strings.Join(elems[:i+1], ""), "/")
+		ret := *u
+		ret.Scheme = "http"
+		ret.Host = "localhost"
+		ret.Path = "/" + strings.Join(elems[i+1:], "")
+		ret.RawPath = ""
+		return &ret, socket, nil
+	}
+	return nil, "", fmt.Errorf("unix:// URLs must name a socket file ending in .sock")
+}
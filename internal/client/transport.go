@@ -2,48 +2,106 @@ package client
 
 import (
 	"fmt"
+	"io"
 	"net/http"
+	"time"
 )
 
 type Authenticator interface {
 	Auth(req *http.Request) error
 }
 
-// ArrTransport is a http.RoundTripper that adds authentication to requests
+// ArrTransport is a http.RoundTripper that adds authentication to requests,
+// retrying them according to its RetryPolicy.
 type ExportarrTransport struct {
-	inner http.RoundTripper
-	auth  Authenticator
+	inner  http.RoundTripper
+	auth   Authenticator
+	policy RetryPolicy
+	socket string // Unix socket the requests are sent to, if any
 }
 
 func NewExportarrTransport(inner http.RoundTripper, auth Authenticator) *ExportarrTransport {
 	return &ExportarrTransport{
-		inner: inner,
-		auth:  auth,
+		inner:  inner,
+		auth:   auth,
+		policy: DefaultRetryPolicy,
 	}
 }
 
+func (t *ExportarrTransport) CloseIdleConnections() {
+	closeIdleConnections(t.inner)
+	closeIdleConnections(t.auth)
+}
+
+// Reauthenticator is an Authenticator holding a session, e.g. a login cookie,
+// which is renewed when the app rejects it.
+type Reauthenticator interface {
+	Authenticator
+	// Invalidate drops the session req was authenticated with, so the next
+	// call to Auth logs in again.
+	Invalidate(req *http.Request)
+}
+
 func (t *ExportarrTransport) RoundTrip(req *http.Request) (*http.Response, error) {
-	if t.auth != nil {
-		err := t.auth.Auth(req)
-		if err != nil {
-			return nil, fmt.Errorf("Error authenticating request: %w", err)
+	orig := req
+	req, err := t.authenticate(orig)
+	if err != nil {
+		return nil, err
+	}
+
+	var b *breaker
+	if t.policy.BreakerThreshold > 0 {
+		b = breakerFor(t.breakerKey(req.URL))
+		if err := b.allow(); err != nil {
+			return nil, err
 		}
 	}
 
-	resp, err := t.inner.RoundTrip(req)
-	if err != nil || resp.StatusCode >= 500 {
-		retries := 2
-		for i := 0; i < retries; i++ {
-			resp, err = t.inner.RoundTrip(req)
-			if err == nil && resp.StatusCode < 500 {
-				return resp, nil
-			}
+	labels := labelsFor(req)
+	resp, err := t.send(req, labels)
+	// A rejected session is renewed once, e.g. when the app restarted and forgot it
+	if ra, ok := reauthenticator(t.auth); ok && err == nil &&
+		(resp.StatusCode == http.StatusUnauthorized || resp.StatusCode == http.StatusForbidden) {
+		drain(resp)
+		ra.Invalidate(req)
+		if req, err = t.authenticate(orig); err != nil {
+			return nil, err
 		}
-		if err != nil {
-			return nil, fmt.Errorf("Error sending HTTP Request: %w", err)
-		} else {
-			return nil, fmt.Errorf("Received Server Error Status Code: %d", resp.StatusCode)
+		resp, err = t.send(req, labels)
+	}
+	for retry := 1; retry < t.policy.Attempts && retryable(resp, err); retry++ {
+		// Requests with a body which can't be rewound can't be sent again
+		if req.Body != nil && req.Body != http.NoBody && req.GetBody == nil {
+			break
+		}
+		d, ok := t.policy.delay(retry, resp)
+		if !ok {
+			break
+		}
+		if err := sleep(req.Context(), d); err != nil {
+			break
 		}
+		drain(resp)
+		retriesTotal.WithLabelValues(labels.app, labels.endpoint).Inc()
+		resp, err = t.send(req, labels)
+	}
+	requestsTotal.WithLabelValues(labels.app, labels.endpoint, statusClass(resp, err)).Inc()
+	if err != nil || resp.StatusCode >= 300 {
+		failuresTotal.WithLabelValues(labels.app, labels.endpoint).Inc()
+	}
+	// Cancelled requests say nothing about the health of the app
+	if b != nil && req.Context().Err() == nil {
+		b.record(!retryable(resp, err), t.policy)
+	}
+
+	if err != nil {
+		return nil, fmt.Errorf("Error sending HTTP Request: %w", err)
+	}
+	if resp.StatusCode >= 300 && resp.Body != nil {
+		resp.Body.Close()
+	}
+	if resp.StatusCode >= 500 {
+		return nil, fmt.Errorf("Received Server Error Status Code: %d", resp.StatusCode)
 	}
 	if resp.StatusCode >= 400 && resp.StatusCode <= 499 {
 		return nil, fmt.Errorf("Received Client Error Status Code: %d", resp.StatusCode)
@@ -57,3 +115,42 @@ func (t *ExportarrTransport) RoundTrip(req *http.Request) (*http.Response, error
 	}
 	return resp, nil
 }
+
+// authenticate returns a copy of req authenticated with the transport's
+// Authenticator, leaving req itself untouched so it can be authenticated again.
+func (t *ExportarrTransport) authenticate(req *http.Request) (*http.Request, error) {
+	if t.auth == nil {
+		return req, nil
+	}
+	ret := req.Clone(req.Context())
+	if err := t.auth.Auth(ret); err != nil {
+		return nil, fmt.Errorf("Error authenticating request: %w", err)
+	}
+	return ret, nil
+}
+
+// drain reads and closes the body of a failed response so its connection can be reused.
+func drain(resp *http.Response) {
+	if resp != nil && resp.Body != nil {
+		io.Copy(io.Discard, resp.Body) //nolint:errcheck
+		resp.Body.Close()
+	}
+}
+
+// send makes a single attempt at req with a fresh copy of its body, observing its latency.
+func (t *ExportarrTransport) send(req *http.Request, labels requestLabels) (*http.Response, error) {
+	attempt := req
+	if req.GetBody != nil {
+		body, err := req.GetBody()
+		if err != nil {
+			return nil, fmt.Errorf("Failed to rewind request body: %w", err)
+		}
+		attempt = req.Clone(req.Context())
+		attempt.Body = body
+	}
+
+	start := time.Now()
+	resp, err := t.inner.RoundTrip(attempt)
+	requestDuration.WithLabelValues(labels.app, labels.endpoint, statusClass(resp, err)).Observe(time.Since(start).Seconds())
+	return resp, err
+}
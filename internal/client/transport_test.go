@@ -1 +1,316 @@
 package client
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	dto "github.com/prometheus/client_model/go"
+	"github.com/stretchr/testify/require"
+)
+
+type roundTripFunc func(req *http.Request) (*http.Response, error)
+
+func (f roundTripFunc) RoundTrip(req *http.Request) (*http.Response, error) {
+	return f(req)
+}
+
+// respondWith returns a RoundTripper answering each attempt with the next status code,
+// where 0 is a transport error.
+func respondWith(codes ...int) roundTripFunc {
+	i := 0
+	return func(req *http.Request) (*http.Response, error) {
+		code := codes[i]
+		i++
+		if code == 0 {
+			return nil, errors.New("connection refused")
+		}
+		return &http.Response{StatusCode: code, Body: http.NoBody, Request: req}, nil
+	}
+}
+
+// observations returns the number of request latencies observed for app.
+func observations(app string) uint64 {
+	ch := make(chan prometheus.Metric, 100)
+	requestDuration.Collect(ch)
+	close(ch)
+	var n uint64
+	for m := range ch {
+		var d dto.Metric
+		if err := m.Write(&d); err != nil {
+			continue
+		}
+		for _, l := range d.GetLabel() {
+			if l.GetName() == "app" && l.GetValue() == app {
+				n += d.GetHistogram().GetSampleCount()
+			}
+		}
+	}
+	return n
+}
+
+func TestRoundTrip_Metrics(t *testing.T) {
+	parameters := []struct {
+		name        string
+		app         string
+		codes       []int
+		shouldError bool
+		retries     float64
+		class       string
+	}{
+		{
+			name:  "success",
+			app:   "metrics-success",
+			codes: []int{200},
+			class: "2xx",
+		},
+		{
+			name:    "retried",
+			app:     "metrics-retried",
+			codes:   []int{500, 0, 200},
+			retries: 2,
+			class:   "2xx",
+		},
+		{
+			name:        "server-error",
+			app:         "metrics-server-error",
+			codes:       []int{503, 503, 503},
+			shouldError: true,
+			retries:     2,
+			class:       "5xx",
+		},
+		{
+			name:        "client-error",
+			app:         "metrics-client-error",
+			codes:       []int{404},
+			shouldError: true,
+			class:       "4xx",
+		},
+		{
+			name:        "transport-error",
+			app:         "metrics-transport-error",
+			codes:       []int{0, 0, 0},
+			shouldError: true,
+			retries:     2,
+			class:       "error",
+		},
+	}
+	for _, param := range parameters {
+		t.Run(param.name, func(t *testing.T) {
+			require := require.New(t)
+			transport := NewExportarrTransport(respondWith(param.codes...), nil)
+
+			ctx := withRequestLabels(context.Background(), param.app, "/wanted/missing")
+			req, err := http.NewRequestWithContext(ctx, "GET", "http://example.com/api/v3/wanted/missing", nil)
+			require.NoError(err)
+			_, err = transport.RoundTrip(req)
+			if param.shouldError {
+				require.Error(err)
+			} else {
+				require.NoError(err)
+			}
+
+			require.Equal(1.0, testutil.ToFloat64(requestsTotal.WithLabelValues(param.app, "wanted/missing", param.class)))
+			require.Equal(param.retries, testutil.ToFloat64(retriesTotal.WithLabelValues(param.app, "wanted/missing")))
+			failures := 0.0
+			if param.shouldError {
+				failures = 1
+			}
+			require.Equal(failures, testutil.ToFloat64(failuresTotal.WithLabelValues(param.app, "wanted/missing")))
+			require.Equal(uint64(len(param.codes)), observations(param.app), "Every attempt should be observed")
+		})
+	}
+}
+
+// noDelay retries immediately, keeping tests fast.
+var noDelay = RetryPolicy{Attempts: 3, MaxBackoff: time.Second}
+
+func TestRoundTrip_RewindsBody(t *testing.T) {
+	require := require.New(t)
+	var bodies []string
+	transport := NewExportarrTransport(roundTripFunc(func(req *http.Request) (*http.Response, error) {
+		b, err := io.ReadAll(req.Body)
+		require.NoError(err)
+		bodies = append(bodies, string(b))
+		code := http.StatusInternalServerError
+		if len(bodies) == 2 {
+			code = http.StatusOK
+		}
+		return &http.Response{StatusCode: code, Body: http.NoBody, Request: req}, nil
+	}), nil)
+	transport.policy = noDelay
+
+	req, err := http.NewRequest("POST", "http://rewind.example.com", strings.NewReader("form=data"))
+	require.NoError(err)
+	_, err = transport.RoundTrip(req)
+	require.NoError(err)
+	require.Equal([]string{"form=data", "form=data"}, bodies, "Every attempt should send the whole body")
+}
+
+func TestRoundTrip_RetryAfter(t *testing.T) {
+	parameters := []struct {
+		name       string
+		retryAfter string
+		attempts   int
+	}{
+		{name: "immediate", retryAfter: "0", attempts: 3},
+		{name: "too-long", retryAfter: "3600", attempts: 1},
+	}
+	for _, param := range parameters {
+		t.Run(param.name, func(t *testing.T) {
+			require := require.New(t)
+			attempts := 0
+			transport := NewExportarrTransport(roundTripFunc(func(req *http.Request) (*http.Response, error) {
+				attempts++
+				return &http.Response{
+					StatusCode: http.StatusTooManyRequests,
+					Header:     http.Header{"Retry-After": []string{param.retryAfter}},
+					Body:       http.NoBody,
+					Request:    req,
+				}, nil
+			}), nil)
+			transport.policy = noDelay
+
+			req, err := http.NewRequest("GET", "http://retry-after.example.com", nil)
+			require.NoError(err)
+			_, err = transport.RoundTrip(req)
+			require.Error(err)
+			require.Equal(param.attempts, attempts)
+		})
+	}
+}
+
+func TestRetryPolicy_Delay(t *testing.T) {
+	require := require.New(t)
+	p := RetryPolicy{Attempts: 5, Backoff: 100 * time.Millisecond, MaxBackoff: 300 * time.Millisecond}
+	for retry, max := range map[int]time.Duration{
+		1: 100 * time.Millisecond,
+		2: 200 * time.Millisecond,
+		3: 300 * time.Millisecond,
+		4: 300 * time.Millisecond,
+	} {
+		d, ok := p.delay(retry, nil)
+		require.True(ok)
+		require.GreaterOrEqual(d, max/2, "retry %d", retry)
+		require.LessOrEqual(d, max, "retry %d", retry)
+	}
+}
+
+func TestRoundTrip_CircuitBreaker(t *testing.T) {
+	require := require.New(t)
+	attempts := 0
+	transport := NewExportarrTransport(roundTripFunc(func(req *http.Request) (*http.Response, error) {
+		attempts++
+		return nil, errors.New("connection refused")
+	}), nil)
+	transport.policy = RetryPolicy{Attempts: 1, BreakerThreshold: 2, BreakerCooldown: time.Hour}
+
+	for i := 0; i < 3; i++ {
+		req, err := http.NewRequest("GET", "http://breaker.example.com/api/v3/queue", nil)
+		require.NoError(err)
+		_, err = transport.RoundTrip(req)
+		require.Error(err)
+	}
+	require.Equal(2, attempts, "Requests shouldn't be sent while the breaker is open")
+	require.NoError(testutil.CollectAndCompare(breakerCollector{}, strings.NewReader(`
+# HELP exportarr_upstream_circuit_breaker_open Whether the circuit breaker of the upstream app is open, stopping requests to it.
+# TYPE exportarr_upstream_circuit_breaker_open gauge
+exportarr_upstream_circuit_breaker_open{url="http://breaker.example.com"} 1
+`)))
+}
+
+// sessionAuth is a Reauthenticator counting its sessions.
+type sessionAuth struct {
+	sessions    int
+	invalidated int
+}
+
+func (a *sessionAuth) Auth(req *http.Request) error {
+	if a.sessions == a.invalidated {
+		a.sessions++
+	}
+	req.Header.Add("X-Session", fmt.Sprint(a.sessions))
+	return nil
+}
+
+func (a *sessionAuth) Invalidate(req *http.Request) {
+	a.invalidated = a.sessions
+}
+
+func TestRoundTrip_Reauthenticates(t *testing.T) {
+	parameters := []struct {
+		name     string
+		auth     Authenticator
+		codes    []int
+		sessions int
+		err      bool
+	}{
+		{
+			name:     "renewed",
+			auth:     &sessionAuth{},
+			codes:    []int{http.StatusUnauthorized, http.StatusOK},
+			sessions: 2,
+		},
+		{
+			name:     "renewed-once",
+			auth:     &sessionAuth{},
+			codes:    []int{http.StatusForbidden, http.StatusForbidden},
+			sessions: 2,
+			err:      true,
+		},
+		{
+			name:     "chained",
+			auth:     Chain(&HeaderAuth{Headers: map[string]string{"X-Test": "1"}}, &sessionAuth{}),
+			codes:    []int{http.StatusUnauthorized, http.StatusOK},
+			sessions: 2,
+		},
+		{
+			name:  "no-session",
+			auth:  &HeaderAuth{Headers: map[string]string{"X-Test": "1"}},
+			codes: []int{http.StatusUnauthorized},
+			err:   true,
+		},
+	}
+	for _, p := range parameters {
+		t.Run(p.name, func(t *testing.T) {
+			require := require.New(t)
+			var sessions []string
+			respond := respondWith(p.codes...)
+			transport := NewExportarrTransport(roundTripFunc(func(req *http.Request) (*http.Response, error) {
+				sessions = append(sessions, req.Header.Get("X-Session"))
+				return respond(req)
+			}), p.auth)
+			transport.policy = noDelay
+
+			req, err := http.NewRequest("GET", "http://session.example.com", nil)
+			require.NoError(err)
+			_, err = transport.RoundTrip(req)
+			if p.err {
+				require.Error(err)
+			} else {
+				require.NoError(err)
+			}
+			require.Len(sessions, len(p.codes), "Rejected requests should be sent again once")
+			if p.sessions > 0 {
+				require.Equal(fmt.Sprint(p.sessions), sessions[len(sessions)-1], "Each attempt should carry only its own session")
+			}
+			require.Empty(req.Header.Get("X-Session"), "The caller's request should not be modified")
+		})
+	}
+}
+
+func TestObserveLogin(t *testing.T) {
+	require := require.New(t)
+	ObserveLogin("login-app", "new", nil)
+	ObserveLogin("login-app", "rejected", errors.New("login failed"))
+	require.Equal(1.0, testutil.ToFloat64(loginsTotal.WithLabelValues("login-app", "new")))
+	require.Equal(1.0, testutil.ToFloat64(loginsTotal.WithLabelValues("login-app", "rejected")))
+	require.Equal(1.0, testutil.ToFloat64(loginFailuresTotal.WithLabelValues("login-app")))
+}
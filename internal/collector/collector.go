@@ -0,0 +1,22 @@
+package collector
+
+import (
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+// Named pairs a collector with the name used to configure it, e.g. "queue" or "sonarr".
+type Named struct {
+	Name string
+	prometheus.Collector
+}
+
+// Filter returns the collectors of named for which enabled returns true.
+func Filter(named []Named, enabled func(name string) bool) []Named {
+	ret := make([]Named, 0, len(named))
+	for _, n := range named {
+		if enabled(n.Name) {
+			ret = append(ret, n)
+		}
+	}
+	return ret
+}
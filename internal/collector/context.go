@@ -0,0 +1,80 @@
+package collector
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	dto "github.com/prometheus/client_model/go"
+)
+
+// ContextCollector is a collector whose upstream requests can be cancelled.
+type ContextCollector interface {
+	prometheus.Collector
+	CollectContext(ctx context.Context, ch chan<- prometheus.Metric)
+}
+
+// CollectContext collects c with ctx, falling back to Collect for collectors
+// which aren't context aware.
+func CollectContext(ctx context.Context, c prometheus.Collector, ch chan<- prometheus.Metric) {
+	if cc, ok := c.(ContextCollector); ok {
+		cc.CollectContext(ctx, ch)
+		return
+	}
+	c.Collect(ch)
+}
+
+// Result is the outcome of collecting a single collector.
+type Result struct {
+	Success  bool
+	Duration time.Duration
+	Err      error // First error reported by the collector
+}
+
+// Collect collects n with ctx into ch, cancelling its upstream requests after
+// timeout when timeout is greater than zero. Collectors report errors as invalid
+// metrics; these are kept out of ch and returned in the Result instead, so one
+// failing collector doesn't fail the whole scrape. A collector which hasn't
+// finished when the context is done is abandoned.
+func Collect(ctx context.Context, n Named, timeout time.Duration, ch chan<- prometheus.Metric) Result {
+	start := time.Now()
+	if timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, timeout)
+		defer cancel()
+	}
+
+	metrics := make(chan prometheus.Metric)
+	go func() {
+		CollectContext(ctx, n.Collector, metrics)
+		close(metrics)
+	}()
+
+	var err error
+	for {
+		select {
+		case m, ok := <-metrics:
+			if !ok {
+				return Result{Success: err == nil, Duration: time.Since(start), Err: err}
+			}
+			if werr := m.Write(&dto.Metric{}); werr != nil {
+				if err == nil {
+					err = werr
+				}
+				continue
+			}
+			ch <- m
+		case <-ctx.Done():
+			// Drain whatever the inner collector still sends so it can exit.
+			go func() {
+				for range metrics {
+				}
+			}()
+			return Result{
+				Duration: time.Since(start),
+				Err:      fmt.Errorf("collector %s: %w", n.Name, ctx.Err()),
+			}
+		}
+	}
+}
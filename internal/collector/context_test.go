@@ -0,0 +1,95 @@
+package collector
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/stretchr/testify/require"
+)
+
+// blockingCollector blocks until its context is done, like a hung upstream request.
+type blockingCollector struct {
+	*testCollector
+	cancelled chan struct{}
+}
+
+func newBlockingCollector() *blockingCollector {
+	return &blockingCollector{
+		testCollector: newTestCollector(),
+		cancelled:     make(chan struct{}),
+	}
+}
+
+func (c *blockingCollector) CollectContext(ctx context.Context, ch chan<- prometheus.Metric) {
+	<-ctx.Done()
+	close(c.cancelled)
+	ch <- prometheus.NewInvalidMetric(c.desc, ctx.Err())
+}
+
+// collectAll runs Collect, returning the metrics it forwarded.
+func collectAll(ctx context.Context, n Named, timeout time.Duration) ([]prometheus.Metric, Result) {
+	ch := make(chan prometheus.Metric)
+	result := make(chan Result, 1)
+	go func() {
+		result <- Collect(ctx, n, timeout, ch)
+		close(ch)
+	}()
+	var metrics []prometheus.Metric
+	for m := range ch {
+		metrics = append(metrics, m)
+	}
+	return metrics, <-result
+}
+
+func TestCollect(t *testing.T) {
+	require := require.New(t)
+	inner := newTestCollector()
+	inner.value = 1
+
+	metrics, result := collectAll(context.Background(), Named{Name: "test", Collector: inner}, time.Second)
+	require.Len(metrics, 1)
+	require.True(result.Success)
+	require.NoError(result.Err)
+}
+
+func TestCollect_Error(t *testing.T) {
+	require := require.New(t)
+	inner := newTestCollector()
+	inner.err = errors.New("upstream down")
+
+	metrics, result := collectAll(context.Background(), Named{Name: "test", Collector: inner}, 0)
+	require.Empty(metrics, "Invalid metrics should not be forwarded")
+	require.False(result.Success)
+	require.ErrorContains(result.Err, "upstream down")
+}
+
+func TestCollect_Timeout(t *testing.T) {
+	require := require.New(t)
+	inner := newBlockingCollector()
+
+	start := time.Now()
+	_, result := collectAll(context.Background(), Named{Name: "test", Collector: inner}, 10*time.Millisecond)
+	require.False(result.Success)
+	require.ErrorIs(result.Err, context.DeadlineExceeded)
+	require.Less(time.Since(start), time.Second)
+
+	select {
+	case <-inner.cancelled:
+	case <-time.After(time.Second):
+		require.Fail("inner collector was not cancelled")
+	}
+}
+
+func TestCollect_Cancelled(t *testing.T) {
+	require := require.New(t)
+	inner := newBlockingCollector()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	_, result := collectAll(ctx, Named{Name: "test", Collector: inner}, 0)
+	require.False(result.Success)
+	require.ErrorIs(result.Err, context.Canceled)
+}
@@ -0,0 +1,119 @@
+package collector
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"go.uber.org/zap"
+)
+
+// ResultReporter is implemented by collectors which report the result of their
+// own last collection rather than of each scrape, like PollingCollector.
+type ResultReporter interface {
+	LastResult() Result
+}
+
+// Instance collects the collectors of a single app instance. Rather than failing
+// the scrape when a collector fails, it reports the success and duration of each
+// collector and whether the app could be reached at all.
+type Instance struct {
+	ctx        context.Context
+	url        string
+	collectors []Named
+	timeout    func(name string) time.Duration
+
+	successMetric  *prometheus.Desc // Whether each collector succeeded
+	durationMetric *prometheus.Desc // How long each collector took
+	upMetric       *prometheus.Desc // Whether the app could be reached
+}
+
+// NewInstance collects the collectors of the app instance at url with ctx,
+// cancelling each collector after its timeout when greater than zero.
+func NewInstance(ctx context.Context, app string, url string, collectors []Named, timeout func(name string) time.Duration) *Instance {
+	return &Instance{
+		ctx:        ctx,
+		url:        url,
+		collectors: collectors,
+		timeout:    timeout,
+		successMetric: prometheus.NewDesc(
+			"exportarr_collector_success",
+			"Whether the last collection of the collector succeeded",
+			[]string{"collector"},
+			prometheus.Labels{"url": url},
+		),
+		durationMetric: prometheus.NewDesc(
+			"exportarr_collector_duration_seconds",
+			"Duration of the last collection of the collector",
+			[]string{"collector"},
+			prometheus.Labels{"url": url},
+		),
+		upMetric: prometheus.NewDesc(
+			prometheus.BuildFQName(app, "", "up"),
+			"Whether the app could be reached, 1 when at least one collector succeeded",
+			nil,
+			prometheus.Labels{"url": url},
+		),
+	}
+}
+
+func (i *Instance) Describe(ch chan<- *prometheus.Desc) {
+	for _, c := range i.collectors {
+		c.Describe(ch)
+	}
+	ch <- i.successMetric
+	ch <- i.durationMetric
+	ch <- i.upMetric
+}
+
+func (i *Instance) Collect(ch chan<- prometheus.Metric) {
+	if len(i.collectors) == 0 {
+		return
+	}
+
+	results := make([]Result, len(i.collectors))
+	var wg sync.WaitGroup
+	for idx, c := range i.collectors {
+		wg.Add(1)
+		go func(idx int, c Named) {
+			defer wg.Done()
+			results[idx] = Collect(i.ctx, c, i.timeout(c.Name), ch)
+			if r, ok := c.Collector.(ResultReporter); ok {
+				results[idx] = r.LastResult()
+				return
+			}
+			i.logResult(c.Name, results[idx])
+		}(idx, c)
+	}
+	wg.Wait()
+
+	up := 0.0
+	for idx, c := range i.collectors {
+		success := 0.0
+		if results[idx].Success {
+			success = 1
+			up = 1
+		}
+		ch <- prometheus.MustNewConstMetric(i.successMetric, prometheus.GaugeValue, success, c.Name)
+		ch <- prometheus.MustNewConstMetric(i.durationMetric, prometheus.GaugeValue, results[idx].Duration.Seconds(), c.Name)
+	}
+	ch <- prometheus.MustNewConstMetric(i.upMetric, prometheus.GaugeValue, up)
+}
+
+func (i *Instance) logResult(name string, r Result) {
+	if r.Err == nil {
+		return
+	}
+	log := zap.S().With("collector", name, "url", i.url)
+	// Collectors log their own errors, only timeouts are reported here.
+	if errors.Is(r.Err, context.DeadlineExceeded) || errors.Is(r.Err, context.Canceled) {
+		log.Errorw("Collector timed out",
+			"duration", r.Duration,
+			"error", r.Err)
+		return
+	}
+	log.Debugw("Collector failed",
+		"error", r.Err)
+}
@@ -0,0 +1,94 @@
+package collector
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/stretchr/testify/require"
+)
+
+func noTimeout(string) time.Duration { return 0 }
+
+func TestInstance(t *testing.T) {
+	require := require.New(t)
+	ok := newTestCollector()
+	ok.value = 1
+	failing := &testCollector{
+		err:  errors.New("upstream down"),
+		desc: prometheus.NewDesc("test_failing", "Failing value", nil, nil),
+	}
+
+	i := NewInstance(context.Background(), "sonarr", "http://localhost", []Named{
+		{Name: "ok", Collector: ok},
+		{Name: "failing", Collector: failing},
+	}, noTimeout)
+
+	// The failing collector doesn't fail the scrape.
+	registry := prometheus.NewRegistry()
+	require.NoError(registry.Register(i))
+	require.NoError(testutil.GatherAndCompare(registry, strings.NewReader(`
+# HELP exportarr_collector_success Whether the last collection of the collector succeeded
+# TYPE exportarr_collector_success gauge
+exportarr_collector_success{collector="failing",url="http://localhost"} 0
+exportarr_collector_success{collector="ok",url="http://localhost"} 1
+# HELP sonarr_up Whether the app could be reached, 1 when at least one collector succeeded
+# TYPE sonarr_up gauge
+sonarr_up{url="http://localhost"} 1
+# HELP test_value Test value
+# TYPE test_value gauge
+test_value 1
+`), "exportarr_collector_success", "sonarr_up", "test_value", "test_failing"))
+	require.Equal(2, testutil.CollectAndCount(i, "exportarr_collector_duration_seconds"))
+}
+
+func TestInstance_Down(t *testing.T) {
+	require := require.New(t)
+	failing := newTestCollector()
+	failing.err = errors.New("upstream down")
+	blocking := newBlockingCollector()
+	blocking.desc = prometheus.NewDesc("test_blocking", "Blocking value", nil, nil)
+
+	i := NewInstance(context.Background(), "sonarr", "http://localhost", []Named{
+		{Name: "failing", Collector: failing},
+		{Name: "blocking", Collector: blocking},
+	}, func(string) time.Duration { return 10 * time.Millisecond })
+
+	require.NoError(testutil.CollectAndCompare(i, strings.NewReader(`
+# HELP exportarr_collector_success Whether the last collection of the collector succeeded
+# TYPE exportarr_collector_success gauge
+exportarr_collector_success{collector="blocking",url="http://localhost"} 0
+exportarr_collector_success{collector="failing",url="http://localhost"} 0
+# HELP sonarr_up Whether the app could be reached, 1 when at least one collector succeeded
+# TYPE sonarr_up gauge
+sonarr_up{url="http://localhost"} 0
+`), "exportarr_collector_success", "sonarr_up"))
+}
+
+func TestInstance_PollingCollector(t *testing.T) {
+	require := require.New(t)
+	inner := newTestCollector()
+	inner.err = errors.New("upstream down")
+	p := NewPollingCollector(Named{Name: "test", Collector: inner}, time.Hour, 0, nil)
+	p.Refresh(context.Background())
+
+	// Pollers report the result of their last refresh, not of serving the snapshot.
+	i := NewInstance(context.Background(), "sonarr", "http://localhost", []Named{{Name: "test", Collector: p}}, noTimeout)
+	require.NoError(testutil.CollectAndCompare(i, strings.NewReader(`
+# HELP exportarr_collector_success Whether the last collection of the collector succeeded
+# TYPE exportarr_collector_success gauge
+exportarr_collector_success{collector="test",url="http://localhost"} 0
+`), "exportarr_collector_success"))
+
+	inner.err = nil
+	p.Refresh(context.Background())
+	require.NoError(testutil.CollectAndCompare(i, strings.NewReader(`
+# HELP exportarr_collector_success Whether the last collection of the collector succeeded
+# TYPE exportarr_collector_success gauge
+exportarr_collector_success{collector="test",url="http://localhost"} 1
+`), "exportarr_collector_success"))
+}
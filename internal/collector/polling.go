@@ -0,0 +1,133 @@
+package collector
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"go.uber.org/zap"
+)
+
+// PollingCollector refreshes an inner collector on its own interval and serves
+// the last successful snapshot on scrape, so slow upstream calls never block
+// a scrape and an unreachable upstream keeps serving its last known values.
+type PollingCollector struct {
+	name        string
+	inner       prometheus.Collector
+	interval    time.Duration
+	timeout     time.Duration
+	started     time.Time
+	lastSuccess time.Time
+	lastResult  Result
+	metrics     []prometheus.Metric
+	mutex       sync.RWMutex
+
+	lastSuccessMetric *prometheus.Desc // Time of the last successful refresh
+	stalenessMetric   *prometheus.Desc // Age of the served snapshot
+}
+
+// NewPollingCollector polls n every interval. Refreshes taking longer than
+// timeout are cancelled, a timeout of 0 disables this.
+func NewPollingCollector(n Named, interval time.Duration, timeout time.Duration, constLabels prometheus.Labels) *PollingCollector {
+	labels := prometheus.Labels{"collector": n.Name}
+	for k, v := range constLabels {
+		labels[k] = v
+	}
+	return &PollingCollector{
+		name:     n.Name,
+		inner:    n.Collector,
+		interval: interval,
+		timeout:  timeout,
+		lastSuccessMetric: prometheus.NewDesc(
+			"exportarr_collector_last_success_timestamp_seconds",
+			"Unix timestamp of the last successful background refresh of the collector",
+			nil,
+			labels,
+		),
+		stalenessMetric: prometheus.NewDesc(
+			"exportarr_collector_staleness_seconds",
+			"Seconds since the served values of the collector were last refreshed successfully",
+			nil,
+			labels,
+		),
+	}
+}
+
+// Start refreshes the collector immediately and then every interval until ctx is done.
+func (p *PollingCollector) Start(ctx context.Context) {
+	p.started = time.Now()
+	go func() {
+		ticker := time.NewTicker(p.interval)
+		defer ticker.Stop()
+		for {
+			p.Refresh(ctx)
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+			}
+		}
+	}()
+}
+
+// Refresh collects the inner collector, replacing the snapshot if it succeeded.
+func (p *PollingCollector) Refresh(ctx context.Context) {
+	log := zap.S().With("collector", p.name)
+
+	ch := make(chan prometheus.Metric)
+	result := make(chan Result, 1)
+	go func() {
+		result <- Collect(ctx, Named{Name: p.name, Collector: p.inner}, p.timeout, ch)
+		close(ch)
+	}()
+
+	var metrics []prometheus.Metric
+	for m := range ch {
+		metrics = append(metrics, m)
+	}
+	r := <-result
+
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+	p.lastResult = r
+	if !r.Success {
+		log.Errorw("Background refresh failed, serving last known values",
+			"error", r.Err)
+		return
+	}
+	p.metrics = metrics
+	p.lastSuccess = time.Now()
+	log.Debugw("Background refresh completed",
+		"duration", r.Duration)
+}
+
+// LastResult returns the result of the last refresh.
+func (p *PollingCollector) LastResult() Result {
+	p.mutex.RLock()
+	defer p.mutex.RUnlock()
+	return p.lastResult
+}
+
+func (p *PollingCollector) Describe(ch chan<- *prometheus.Desc) {
+	p.inner.Describe(ch)
+	ch <- p.lastSuccessMetric
+	ch <- p.stalenessMetric
+}
+
+func (p *PollingCollector) Collect(ch chan<- prometheus.Metric) {
+	p.mutex.RLock()
+	defer p.mutex.RUnlock()
+	for _, m := range p.metrics {
+		ch <- m
+	}
+
+	var lastSuccess float64
+	staleness := time.Since(p.started)
+	if !p.lastSuccess.IsZero() {
+		lastSuccess = float64(p.lastSuccess.UnixNano()) / 1e9
+		staleness = time.Since(p.lastSuccess)
+	}
+	ch <- prometheus.MustNewConstMetric(p.lastSuccessMetric, prometheus.GaugeValue, lastSuccess)
+	ch <- prometheus.MustNewConstMetric(p.stalenessMetric, prometheus.GaugeValue, staleness.Seconds())
+}
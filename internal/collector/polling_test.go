@@ -0,0 +1,76 @@
+package collector
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/stretchr/testify/require"
+)
+
+type testCollector struct {
+	value float64
+	err   error
+	desc  *prometheus.Desc
+}
+
+func newTestCollector() *testCollector {
+	return &testCollector{
+		desc: prometheus.NewDesc("test_value", "Test value", nil, nil),
+	}
+}
+
+func (c *testCollector) Describe(ch chan<- *prometheus.Desc) {
+	ch <- c.desc
+}
+
+func (c *testCollector) Collect(ch chan<- prometheus.Metric) {
+	if c.err != nil {
+		ch <- prometheus.NewInvalidMetric(c.desc, c.err)
+		return
+	}
+	ch <- prometheus.MustNewConstMetric(c.desc, prometheus.GaugeValue, c.value)
+}
+
+func TestPollingCollector(t *testing.T) {
+	require := require.New(t)
+	inner := newTestCollector()
+	p := NewPollingCollector(Named{Name: "test", Collector: inner}, time.Hour, 0, prometheus.Labels{"url": "http://localhost"})
+
+	// Nothing has been refreshed yet, only the self metrics are served.
+	require.Equal(2, testutil.CollectAndCount(p))
+
+	inner.value = 1
+	p.Refresh(context.Background())
+	require.NoError(testutil.CollectAndCompare(p, strings.NewReader(`
+# HELP test_value Test value
+# TYPE test_value gauge
+test_value 1
+`), "test_value"))
+	lastSuccess := p.lastSuccess
+	require.False(lastSuccess.IsZero())
+
+	// Failed refreshes keep serving the last known good values.
+	inner.value = 2
+	inner.err = errors.New("upstream down")
+	p.Refresh(context.Background())
+	require.NoError(testutil.CollectAndCompare(p, strings.NewReader(`
+# HELP test_value Test value
+# TYPE test_value gauge
+test_value 1
+`), "test_value"))
+	require.Equal(lastSuccess, p.lastSuccess)
+
+	inner.err = nil
+	p.Refresh(context.Background())
+	require.NoError(testutil.CollectAndCompare(p, strings.NewReader(`
+# HELP test_value Test value
+# TYPE test_value gauge
+test_value 2
+`), "test_value"))
+	require.True(p.lastSuccess.After(lastSuccess))
+}
@@ -1,14 +1,18 @@
 package commands
 
 import (
+	"context"
 	"fmt"
 	"os"
 
-	"github.com/prometheus/client_golang/prometheus"
 	"github.com/spf13/cobra"
+	"go.uber.org/zap"
 
 	"github.com/onedr0p/exportarr/internal/arr/collector"
 	"github.com/onedr0p/exportarr/internal/arr/config"
+	base_client "github.com/onedr0p/exportarr/internal/client"
+	base_collector "github.com/onedr0p/exportarr/internal/collector"
+	base_config "github.com/onedr0p/exportarr/internal/config"
 )
 
 func init() {
@@ -18,7 +22,9 @@ func init() {
 	config.RegisterArrFlags(readarrCmd.PersistentFlags())
 	config.RegisterArrFlags(prowlarrCmd.PersistentFlags())
 	config.RegisterArrFlags(bazarrCmd.PersistentFlags())
+	config.RegisterArrFlags(autoCmd.PersistentFlags())
 	config.RegisterProwlarrFlags(prowlarrCmd.PersistentFlags())
+	config.RegisterProwlarrFlags(autoCmd.PersistentFlags())
 	config.RegisterBazarrFlags(bazarrCmd.PersistentFlags())
 
 	rootCmd.AddCommand(
@@ -28,11 +34,13 @@ func init() {
 		readarrCmd,
 		bazarrCmd,
 		prowlarrCmd,
+		autoCmd,
 	)
 }
 
 func UsageOnError(cmd *cobra.Command, err error) {
 	if err != nil {
+		err = conf.File().Explain(err)
 		fmt.Fprintln(os.Stderr, err)
 		if err := cmd.Usage(); err != nil {
 			panic(err)
@@ -41,29 +49,46 @@ func UsageOnError(cmd *cobra.Command, err error) {
 	}
 }
 
+// loadArr loads the config of the *arr app of cmd. extra loads and validates
+// the settings specific to the app, if it has any.
+func loadArr(cmd *cobra.Command, extra func(c *config.ArrConfig) error) loadFunc {
+	return func(ctx context.Context, conf *base_config.Config) (*state, error) {
+		c, err := config.LoadArrConfig(*conf, cmd.PersistentFlags())
+		if err != nil {
+			return nil, err
+		}
+		c.ApiVersion = config.DefaultApiVersion(c.App)
+		if err := c.Validate(); err != nil {
+			return nil, err
+		}
+		c.Clients = base_client.NewPool()
+		d := &detection{}
+		d.detect(ctx, c)
+		if extra != nil {
+			if err := extra(c); err != nil {
+				return nil, err
+			}
+		}
+		return &state{
+			instances: []instance{
+				{app: c.App, url: c.URL, collectors: prepareCollectors(ctx, conf, c.URL, arrCollectors(c))},
+			},
+			files:   c.Files(),
+			clients: c.Clients,
+			detect: func(ctx context.Context) {
+				d.detect(ctx, c)
+			},
+		}, nil
+	}
+}
+
 var radarrCmd = &cobra.Command{
 	Use:     "radarr",
 	Aliases: []string{"r"},
 	Short:   "Prometheus Exporter for Radarr",
 	Long:    "Prometheus Exporter for Radarr.",
 	RunE: func(cmd *cobra.Command, args []string) error {
-		c, err := config.LoadArrConfig(*conf, cmd.PersistentFlags())
-		if err != nil {
-			return err
-		}
-		c.ApiVersion = "v3"
-		UsageOnError(cmd, c.Validate())
-
-		serveHttp(func(r prometheus.Registerer) {
-			r.MustRegister(
-				collector.NewRadarrCollector(c),
-				collector.NewQueueCollector(c),
-				collector.NewHistoryCollector(c),
-				collector.NewRootFolderCollector(c),
-				collector.NewSystemStatusCollector(c),
-				collector.NewSystemHealthCollector(c),
-			)
-		})
+		serveHttp(cmd, loadArr(cmd, nil))
 		return nil
 	},
 }
@@ -74,23 +99,7 @@ var sonarrCmd = &cobra.Command{
 	Short:   "Prometheus Exporter for Sonarr",
 	Long:    "Prometheus Exporter for Sonarr.",
 	RunE: func(cmd *cobra.Command, args []string) error {
-		c, err := config.LoadArrConfig(*conf, cmd.PersistentFlags())
-		if err != nil {
-			return err
-		}
-		c.ApiVersion = "v3"
-		UsageOnError(cmd, c.Validate())
-
-		serveHttp(func(r prometheus.Registerer) {
-			r.MustRegister(
-				collector.NewSonarrCollector(c),
-				collector.NewQueueCollector(c),
-				collector.NewHistoryCollector(c),
-				collector.NewRootFolderCollector(c),
-				collector.NewSystemStatusCollector(c),
-				collector.NewSystemHealthCollector(c),
-			)
-		})
+		serveHttp(cmd, loadArr(cmd, nil))
 		return nil
 	},
 }
@@ -100,23 +109,7 @@ var lidarrCmd = &cobra.Command{
 	Short: "Prometheus Exporter for Lidarr",
 	Long:  "Prometheus Exporter for Lidarr.",
 	RunE: func(cmd *cobra.Command, args []string) error {
-		c, err := config.LoadArrConfig(*conf, cmd.PersistentFlags())
-		if err != nil {
-			return err
-		}
-		c.ApiVersion = "v1"
-		UsageOnError(cmd, c.Validate())
-
-		serveHttp(func(r prometheus.Registerer) {
-			r.MustRegister(
-				collector.NewLidarrCollector(c),
-				collector.NewQueueCollector(c),
-				collector.NewHistoryCollector(c),
-				collector.NewRootFolderCollector(c),
-				collector.NewSystemStatusCollector(c),
-				collector.NewSystemHealthCollector(c),
-			)
-		})
+		serveHttp(cmd, loadArr(cmd, nil))
 		return nil
 	},
 }
@@ -127,23 +120,7 @@ var readarrCmd = &cobra.Command{
 	Short:   "Prometheus Exporter for Readarr",
 	Long:    "Prometheus Exporter for Readarr.",
 	RunE: func(cmd *cobra.Command, args []string) error {
-		c, err := config.LoadArrConfig(*conf, cmd.PersistentFlags())
-		if err != nil {
-			return err
-		}
-		c.ApiVersion = "v1"
-		UsageOnError(cmd, c.Validate())
-
-		serveHttp(func(r prometheus.Registerer) {
-			r.MustRegister(
-				collector.NewReadarrCollector(c),
-				collector.NewQueueCollector(c),
-				collector.NewHistoryCollector(c),
-				collector.NewRootFolderCollector(c),
-				collector.NewSystemStatusCollector(c),
-				collector.NewSystemHealthCollector(c),
-			)
-		})
+		serveHttp(cmd, loadArr(cmd, nil))
 		return nil
 	},
 }
@@ -154,22 +131,12 @@ var bazarrCmd = &cobra.Command{
 	Short:   "Prometheus Exporter for Bazarr",
 	Long:    "Prometheus Exporter for Bazarr.",
 	RunE: func(cmd *cobra.Command, args []string) error {
-		c, err := config.LoadArrConfig(*conf, cmd.PersistentFlags())
-		if err != nil {
-			return err
-		}
-		c.ApiVersion = ""
-		if err := c.LoadBazarrConfig(cmd.PersistentFlags()); err != nil {
-			return err
-		}
-		UsageOnError(cmd, c.Validate())
-		UsageOnError(cmd, c.Bazarr.Validate())
-
-		serveHttp(func(r prometheus.Registerer) {
-			r.MustRegister(
-				collector.NewBazarrCollector(c),
-			)
-		})
+		serveHttp(cmd, loadArr(cmd, func(c *config.ArrConfig) error {
+			if err := c.LoadBazarrConfig(cmd.PersistentFlags()); err != nil {
+				return err
+			}
+			return c.Bazarr.Validate()
+		}))
 		return nil
 	},
 }
@@ -180,29 +147,122 @@ var prowlarrCmd = &cobra.Command{
 	Short:   "Prometheus Exporter for Prowlarr",
 	Long:    "Prometheus Exporter for Prowlarr.",
 	RunE: func(cmd *cobra.Command, args []string) error {
-		c, err := config.LoadArrConfig(*conf, cmd.PersistentFlags())
-		if err != nil {
-			return err
-		}
-		c.ApiVersion = "v1"
+		serveHttp(cmd, loadArr(cmd, loadProwlarr(cmd)))
+		return nil
+	},
+}
+
+var autoCmd = &cobra.Command{
+	Use:   "auto",
+	Short: "Prometheus Exporter for the *arr app of a config.xml",
+	Long: `Prometheus Exporter for the *arr app which wrote the config.xml given with --config.
+The app is detected from the config.xml, and the URL defaults to the address the app listens on.`,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		serveHttp(cmd, loadArr(cmd, func(c *config.ArrConfig) error {
+			zap.S().Infow("Detected app from config.xml",
+				"app", c.App,
+				"instance", c.InstanceName,
+				"url", c.URL)
+			if c.App == "prowlarr" {
+				return loadProwlarr(cmd)(c)
+			}
+			return nil
+		}))
+		return nil
+	},
+}
+
+// loadProwlarr loads and validates the settings specific to Prowlarr.
+func loadProwlarr(cmd *cobra.Command) func(c *config.ArrConfig) error {
+	return func(c *config.ArrConfig) error {
 		if err := c.LoadProwlarrConfig(cmd.PersistentFlags()); err != nil {
 			return err
 		}
-		if err := c.Prowlarr.Validate(); err != nil {
-			return err
+		return c.Prowlarr.Validate()
+	}
+}
+
+// commandApp returns the app whose settings cmd loads. The auto command
+// detects it from the config.xml it's given, read from the config file, the
+// environment or flags like the other settings.
+func commandApp(cmd *cobra.Command) (string, error) {
+	if cmd.Name() != "auto" {
+		return cmd.Name(), nil
+	}
+	conf, err := base_config.LoadConfig(cmd.Name(), cmd.Root().PersistentFlags())
+	if err != nil {
+		return "", err
+	}
+	path, err := config.ConfigPath(*conf, cmd.PersistentFlags())
+	if err != nil {
+		return "", err
+	}
+	if path == "" {
+		return "", fmt.Errorf("config is required to detect the app")
+	}
+	return config.DetectApp(path)
+}
+
+// arrCollectors returns the enabled collectors of the app configured in c.
+func arrCollectors(c *config.ArrConfig) []base_collector.Named {
+	var named []base_collector.Named
+	switch c.App {
+	case "radarr":
+		named = []base_collector.Named{
+			{Name: "radarr", Collector: collector.NewRadarrCollector(c)},
+			{Name: "queue", Collector: collector.NewQueueCollector(c)},
+			{Name: "cutoff", Collector: collector.NewCutoffCollector(c)},
+			{Name: "missing", Collector: collector.NewMissingCollector(c)},
+			{Name: "history", Collector: collector.NewHistoryCollector(c)},
+			{Name: "rootfolder", Collector: collector.NewRootFolderCollector(c)},
+			{Name: "status", Collector: collector.NewSystemStatusCollector(c)},
+			{Name: "health", Collector: collector.NewSystemHealthCollector(c)},
 		}
-		UsageOnError(cmd, c.Validate())
-		UsageOnError(cmd, c.Prowlarr.Validate())
-
-		serveHttp(func(r prometheus.Registerer) {
-			r.MustRegister(
-				collector.NewProwlarrCollector(c),
-				collector.NewHistoryCollector(c),
-				collector.NewSystemStatusCollector(c),
-				collector.NewSystemHealthCollector(c,
-					collector.NewUnavailableIndexerEmitter(c.URL)),
-			)
-		})
-		return nil
-	},
+	case "sonarr":
+		named = []base_collector.Named{
+			{Name: "sonarr", Collector: collector.NewSonarrCollector(c)},
+			{Name: "queue", Collector: collector.NewQueueCollector(c)},
+			{Name: "cutoff", Collector: collector.NewCutoffCollector(c)},
+			{Name: "missing", Collector: collector.NewMissingCollector(c)},
+			{Name: "history", Collector: collector.NewHistoryCollector(c)},
+			{Name: "rootfolder", Collector: collector.NewRootFolderCollector(c)},
+			{Name: "status", Collector: collector.NewSystemStatusCollector(c)},
+			{Name: "health", Collector: collector.NewSystemHealthCollector(c)},
+		}
+	case "lidarr":
+		named = []base_collector.Named{
+			{Name: "lidarr", Collector: collector.NewLidarrCollector(c)},
+			{Name: "queue", Collector: collector.NewQueueCollector(c)},
+			{Name: "cutoff", Collector: collector.NewCutoffCollector(c)},
+			{Name: "missing", Collector: collector.NewMissingCollector(c)},
+			{Name: "history", Collector: collector.NewHistoryCollector(c)},
+			{Name: "rootfolder", Collector: collector.NewRootFolderCollector(c)},
+			{Name: "status", Collector: collector.NewSystemStatusCollector(c)},
+			{Name: "health", Collector: collector.NewSystemHealthCollector(c)},
+		}
+	case "readarr":
+		named = []base_collector.Named{
+			{Name: "readarr", Collector: collector.NewReadarrCollector(c)},
+			{Name: "queue", Collector: collector.NewQueueCollector(c)},
+			{Name: "cutoff", Collector: collector.NewCutoffCollector(c)},
+			{Name: "missing", Collector: collector.NewMissingCollector(c)},
+			{Name: "history", Collector: collector.NewHistoryCollector(c)},
+			{Name: "rootfolder", Collector: collector.NewRootFolderCollector(c)},
+			{Name: "status", Collector: collector.NewSystemStatusCollector(c)},
+			{Name: "health", Collector: collector.NewSystemHealthCollector(c)},
+		}
+	case "bazarr":
+		named = []base_collector.Named{
+			{Name: "bazarr", Collector: collector.NewBazarrCollector(c)},
+		}
+	case "prowlarr":
+		named = []base_collector.Named{
+			{Name: "prowlarr", Collector: collector.NewProwlarrCollector(c)},
+			{Name: "history", Collector: collector.NewHistoryCollector(c)},
+			{Name: "status", Collector: collector.NewSystemStatusCollector(c)},
+			{Name: "health", Collector: collector.NewSystemHealthCollector(c,
+				collector.NewUnavailableIndexerEmitter(c.URL))},
+		}
+	}
+	return base_collector.Filter(named, c.CollectorEnabled)
 }
@@ -1,10 +1,18 @@
 package commands
 
 import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
 	"testing"
+	"time"
 
 	"github.com/onedr0p/exportarr/internal/arr/config"
+	base_client "github.com/onedr0p/exportarr/internal/client"
+	base_collector "github.com/onedr0p/exportarr/internal/collector"
 	base_config "github.com/onedr0p/exportarr/internal/config"
+	"github.com/prometheus/client_golang/prometheus/testutil"
 	"github.com/spf13/pflag"
 	"github.com/stretchr/testify/require"
 )
@@ -53,3 +61,90 @@ func TestBackwardsCompatibility(t *testing.T) {
 	}
 
 }
+
+func TestArrCollectors(t *testing.T) {
+	for app, names := range config.AppCollectors {
+		t.Run(app, func(t *testing.T) {
+			require := require.New(t)
+			c := &config.ArrConfig{App: app, URL: "http://localhost", Collectors: names}
+
+			var got []string
+			for _, n := range arrCollectors(c) {
+				got = append(got, n.Name)
+			}
+			require.Equal(names, got, "AppCollectors must list every collector of the app")
+
+			c.Collectors = nil
+			got = nil
+			for _, n := range arrCollectors(c) {
+				got = append(got, n.Name)
+			}
+			require.NotContains(got, "cutoff", "cutoff must be opt-in")
+			require.NotContains(got, "missing", "missing must be opt-in")
+
+			c.Collectors = names[:1]
+			c.DisableCollectors = names[1:]
+			got = nil
+			for _, n := range arrCollectors(c) {
+				got = append(got, n.Name)
+			}
+			require.Equal(names[:1], got)
+
+			c.Collectors = nil
+			got = nil
+			for _, n := range arrCollectors(c) {
+				got = append(got, n.Name)
+			}
+			require.Equal(names[:1], got)
+		})
+	}
+}
+
+func TestArrCollectors_Unreachable(t *testing.T) {
+	require := require.New(t)
+	c := &config.ArrConfig{
+		App:         "sonarr",
+		ApiVersion:  "v3",
+		URL:         "http://127.0.0.1:1",
+		ApiKey:      "abcdef0123456789abcdef0123456789",
+		RetryPolicy: &base_client.RetryPolicy{Attempts: 1},
+	}
+	i := base_collector.NewInstance(context.Background(), c.App, c.URL, arrCollectors(c),
+		func(string) time.Duration { return 5 * time.Second })
+
+	require.NoError(testutil.CollectAndCompare(i, strings.NewReader(`
+# HELP sonarr_up Whether the app could be reached, 1 when at least one collector succeeded
+# TYPE sonarr_up gauge
+sonarr_up{url="http://127.0.0.1:1"} 0
+`), "sonarr_up"), "An unreachable app should be down")
+}
+
+func TestCommandApp(t *testing.T) {
+	require := require.New(t)
+	app, err := commandApp(sonarrCmd)
+	require.NoError(err)
+	require.Equal("sonarr", app)
+
+	t.Setenv("EXPORTARR_CONFIG", "")
+	_, err = commandApp(autoCmd)
+	require.Error(err, "auto should require a config.xml")
+
+	t.Setenv("EXPORTARR_CONFIG", "../arr/config/test_fixtures/config.test_xml")
+	app, err = commandApp(autoCmd)
+	require.NoError(err)
+	require.Equal("radarr", app, "The app should be detected from the config.xml's port")
+
+	os.Unsetenv("EXPORTARR_CONFIG") // restored by t.Setenv
+	t.Setenv("CONFIG", "../arr/config/test_fixtures/config.test_xml")
+	app, err = commandApp(autoCmd)
+	require.NoError(err)
+	require.Equal("radarr", app, "The legacy CONFIG variable should be read")
+
+	os.Unsetenv("CONFIG")
+	configFile := filepath.Join(t.TempDir(), "exportarr.yaml")
+	require.NoError(os.WriteFile(configFile, []byte("config: ../arr/config/test_fixtures/config.test_xml\n"), 0o600))
+	t.Setenv("EXPORTARR_CONFIG_FILE", configFile)
+	app, err = commandApp(autoCmd)
+	require.NoError(err)
+	require.Equal("radarr", app, "The config should be read from the config file")
+}
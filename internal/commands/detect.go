@@ -0,0 +1,101 @@
+package commands
+
+import (
+	"context"
+	"strings"
+	"sync"
+	"time"
+
+	"go.uber.org/zap"
+
+	"github.com/onedr0p/exportarr/internal/arr/client"
+	"github.com/onedr0p/exportarr/internal/arr/config"
+)
+
+// DETECT_RETRY is how long a failed detection of an app is kept before the
+// next scrape or probe tries again.
+var DETECT_RETRY = 5 * time.Minute
+
+// detection is what was detected about an app. When the app can't be reached,
+// e.g. because exportarr started first, detection is retried lazily.
+type detection struct {
+	mutex  sync.Mutex
+	api    *config.APIInfo
+	failed time.Time // When detection last failed, zero if it wasn't tried yet
+}
+
+// detect sets what was detected about the app on c, detecting it first unless
+// it was already detected or the last attempt failed less than DETECT_RETRY ago.
+func (d *detection) detect(ctx context.Context, c *config.ArrConfig) {
+	d.mutex.Lock()
+	defer d.mutex.Unlock()
+	if d.api == nil && (d.failed.IsZero() || time.Since(d.failed) >= DETECT_RETRY) {
+		if d.api = detectAPI(ctx, c); d.api == nil {
+			d.failed = time.Now()
+		}
+	}
+	if d.api != nil && c.API() != d.api {
+		c.SetAPI(d.api)
+	}
+}
+
+// detectAPI detects the app configured by c, returning nil when it couldn't.
+func detectAPI(ctx context.Context, c *config.ArrConfig) *config.APIInfo {
+	if err := client.DetectAPI(ctx, c); err != nil {
+		zap.S().Warnw("Couldn't detect the app, assuming all of its features until it can be",
+			"app", c.App,
+			"url", c.URL,
+			"error", err)
+		return nil
+	}
+	api := c.API()
+	if api != nil {
+		zap.S().Infow("Detected app",
+			"app", c.App,
+			"url", c.URL,
+			"version", api.Version)
+	}
+	return api
+}
+
+// detections keeps what was detected about probed targets, so only their first
+// probe queries the app for it.
+type detections struct {
+	mutex   sync.Mutex
+	results map[string]*detection
+}
+
+func newDetections() *detections {
+	return &detections{results: map[string]*detection{}}
+}
+
+// detect detects the app of the target configured by c with module, or
+// applies what was detected on an earlier probe.
+func (n *detections) detect(ctx context.Context, module string, c *config.ArrConfig) {
+	if module == "" {
+		module = "default"
+	}
+	key := strings.Join([]string{module, c.App, c.URL}, " ")
+	n.mutex.Lock()
+	d, ok := n.results[key]
+	if !ok {
+		d = &detection{}
+		n.results[key] = d
+	}
+	n.mutex.Unlock()
+	d.detect(ctx, c)
+}
+
+// detectAll detects the apps of configs in parallel, so unreachable apps don't
+// each delay the others by up to DetectTimeout. ds holds the detection of each config.
+func detectAll(ctx context.Context, ds []*detection, configs []*config.ArrConfig) {
+	var wg sync.WaitGroup
+	for i := range configs {
+		wg.Add(1)
+		go func(d *detection, c *config.ArrConfig) {
+			defer wg.Done()
+			d.detect(ctx, c)
+		}(ds[i], configs[i])
+	}
+	wg.Wait()
+}
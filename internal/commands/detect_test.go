@@ -0,0 +1,112 @@
+package commands
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/stretchr/testify/require"
+
+	"github.com/onedr0p/exportarr/internal/arr/config"
+	base_config "github.com/onedr0p/exportarr/internal/config"
+)
+
+// newTestSonarr serves the system/status of Sonarr, or 503 while down is set.
+func newTestSonarr(t *testing.T, requests *atomic.Int32, down *atomic.Bool) *httptest.Server {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests.Add(1)
+		if down.Load() {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+		if r.URL.Path != "/api/v3/system/status" {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		w.Write([]byte(`{"appName":"Sonarr","version":"4.0.1.929"}`)) //nolint:errcheck
+	}))
+	t.Cleanup(ts.Close)
+	return ts
+}
+
+func testSonarrConfig(url string) *config.ArrConfig {
+	return &config.ArrConfig{App: "sonarr", URL: url, ApiKey: "abcdef0123456789abcdef0123456789", ApiVersion: "v3", ApiRootPath: "/"}
+}
+
+func TestDetection(t *testing.T) {
+	require := require.New(t)
+	var requests atomic.Int32
+	var down atomic.Bool
+	ts := newTestSonarr(t, &requests, &down)
+	DETECT_RETRY = 50 * time.Millisecond
+	t.Cleanup(func() { DETECT_RETRY = 5 * time.Minute })
+
+	// The app isn't up yet when exportarr starts
+	down.Store(true)
+	c := testSonarrConfig(ts.URL)
+	d := &detection{}
+	d.detect(context.Background(), c)
+	require.Nil(c.API())
+	failed := requests.Load()
+
+	down.Store(false)
+	d.detect(context.Background(), c)
+	require.Nil(c.API())
+	require.Equal(failed, requests.Load(), "failures should only be retried after DETECT_RETRY")
+
+	time.Sleep(60 * time.Millisecond)
+	d.detect(context.Background(), c)
+	require.NotNil(c.API())
+	require.Equal("4.0.1.929", c.API().Version)
+
+	detected := requests.Load()
+	d.detect(context.Background(), c)
+	require.Equal(detected, requests.Load(), "apps should only be detected once")
+}
+
+func TestDetections(t *testing.T) {
+	require := require.New(t)
+	var requests atomic.Int32
+	var down atomic.Bool
+	ts := newTestSonarr(t, &requests, &down)
+
+	n := newDetections()
+	c := testSonarrConfig(ts.URL)
+	n.detect(context.Background(), "", c)
+	require.NotNil(c.API())
+	require.Equal("4.0.1.929", c.API().Version)
+	require.Equal(int32(1), requests.Load())
+
+	c = testSonarrConfig(ts.URL)
+	n.detect(context.Background(), "default", c)
+	require.Equal("4.0.1.929", c.API().Version, "later probes should reuse the detected app")
+	require.Equal(int32(1), requests.Load())
+
+	down.Store(true)
+	c = testSonarrConfig(ts.URL)
+	n.detect(context.Background(), "other", c)
+	require.Nil(c.API(), "modules are detected on their own")
+}
+
+func TestMetricsHandler_Detect(t *testing.T) {
+	require := require.New(t)
+	var detects atomic.Int32
+	load := func(ctx context.Context, conf *base_config.Config) (*state, error) {
+		return &state{
+			detect: func(ctx context.Context) {
+				detects.Add(1)
+			},
+		}, nil
+	}
+	r, err := newReloader(&base_config.Config{}, func() (*base_config.Config, error) { return &base_config.Config{}, nil }, load)
+	require.NoError(err)
+
+	rec := httptest.NewRecorder()
+	metricsHandler(prometheus.NewRegistry(), r).ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/metrics", nil))
+	require.Equal(http.StatusOK, rec.Code)
+	require.Equal(int32(1), detects.Load(), "scrapes should retry detecting the apps")
+}
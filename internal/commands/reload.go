@@ -0,0 +1,224 @@
+package commands
+
+import (
+	"context"
+	"os"
+	"os/signal"
+	"path/filepath"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"syscall"
+	"time"
+
+	"github.com/fsnotify/fsnotify"
+	"github.com/prometheus/client_golang/prometheus"
+	"go.uber.org/zap"
+
+	"github.com/onedr0p/exportarr/internal/client"
+	"github.com/onedr0p/exportarr/internal/config"
+	"github.com/onedr0p/exportarr/internal/handlers"
+)
+
+// RELOAD_DEBOUNCE is how long changes to watched files settle before a
+// reload, as editors and Kubernetes update files in several steps.
+var RELOAD_DEBOUNCE = time.Second
+
+// state is what the exporter serves, rebuilt from the config on every reload.
+type state struct {
+	conf      *config.Config
+	instances []instance
+	probe     handlers.ProbeFunc        // nil when the command doesn't support probes
+	files     []string                  // Files the state was loaded from, watched for changes
+	clients   *client.Pool              // Clients of the instances, closed with the state
+	detect    func(ctx context.Context) // Retries detecting the apps which couldn't be detected yet, if set
+	stop      context.CancelFunc        // Stops the background pollers of the state and closes its clients
+}
+
+// loadFunc loads the command's own config on top of conf and builds the state
+// from it. Background pollers must be bound to ctx.
+type loadFunc func(ctx context.Context, conf *config.Config) (*state, error)
+
+var (
+	reloadsTotal = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Name: "exportarr_config_reloads_total",
+		Help: "Total number of config reloads, by result.",
+	}, []string{"result"})
+	lastReloadSuccessful = prometheus.NewGauge(prometheus.GaugeOpts{
+		Name: "exportarr_config_last_reload_successful",
+		Help: "Whether the last config reload succeeded.",
+	})
+	lastReloadSuccess = prometheus.NewGauge(prometheus.GaugeOpts{
+		Name: "exportarr_config_last_reload_success_timestamp_seconds",
+		Help: "Unix timestamp of the last successful config reload.",
+	})
+)
+
+// reloader holds the current state, replacing it atomically when the config
+// is reloaded so scrapes in flight keep the state they started with.
+type reloader struct {
+	loadConf func() (*config.Config, error)
+	load     loadFunc
+	current  atomic.Pointer[state]
+	mutex    sync.Mutex // Serializes reloads
+}
+
+// newReloader builds the initial state from the already loaded conf.
+// Reloads load the base config again with loadConf first.
+func newReloader(conf *config.Config, loadConf func() (*config.Config, error), load loadFunc) (*reloader, error) {
+	r := &reloader{
+		loadConf: loadConf,
+		load:     load,
+	}
+	s, err := r.build(conf)
+	if err != nil {
+		return nil, err
+	}
+	r.current.Store(s)
+	lastReloadSuccessful.Set(1)
+	lastReloadSuccess.SetToCurrentTime()
+	return r, nil
+}
+
+func (r *reloader) build(conf *config.Config) (*state, error) {
+	ctx, cancel := context.WithCancel(context.Background())
+	s, err := r.load(ctx, conf)
+	if err != nil {
+		cancel()
+		return nil, err
+	}
+	s.conf = conf
+	s.files = append(conf.Files(), s.files...)
+	s.stop = func() {
+		cancel()
+		s.clients.Close()
+	}
+	return s, nil
+}
+
+// reloadMetrics returns the collectors reporting the result of reloads.
+func reloadMetrics() []prometheus.Collector {
+	return []prometheus.Collector{reloadsTotal, lastReloadSuccessful, lastReloadSuccess}
+}
+
+// State returns the current state.
+func (r *reloader) State() *state {
+	return r.current.Load()
+}
+
+// Reload loads the config again and swaps it in when it is valid. The
+// current state is kept when it isn't.
+func (r *reloader) Reload() error {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	conf, err := r.loadConf()
+	var s *state
+	if err == nil {
+		s, err = r.build(conf)
+		err = conf.File().Explain(err)
+	}
+	if err != nil {
+		reloadsTotal.WithLabelValues("failure").Inc()
+		lastReloadSuccessful.Set(0)
+		zap.S().Errorw("Config reload failed, keeping the current config",
+			"error", err)
+		return err
+	}
+
+	old := r.current.Swap(s)
+	old.stop()
+	reloadsTotal.WithLabelValues("success").Inc()
+	lastReloadSuccessful.Set(1)
+	lastReloadSuccess.SetToCurrentTime()
+	zap.S().Infow("Config reloaded",
+		"files", s.files)
+	return nil
+}
+
+// Watch reloads on SIGHUP and when the files of the current state change,
+// until ctx is done.
+func (r *reloader) Watch(ctx context.Context) {
+	sighup := make(chan os.Signal, 1)
+	signal.Notify(sighup, syscall.SIGHUP)
+	defer signal.Stop(sighup)
+
+	watcher, err := fsnotify.NewWatcher()
+	if err != nil {
+		zap.S().Errorw("Couldn't watch config files, only reloading on SIGHUP",
+			"error", err)
+	} else {
+		defer watcher.Close()
+		r.watchFiles(watcher)
+	}
+
+	reload := func() {
+		if err := r.Reload(); err == nil {
+			r.watchFiles(watcher)
+		}
+	}
+
+	var (
+		events  <-chan fsnotify.Event
+		errs    <-chan error
+		pending <-chan time.Time
+	)
+	if watcher != nil {
+		events = watcher.Events
+		errs = watcher.Errors
+	}
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-sighup:
+			zap.S().Infow("Reloading config on SIGHUP")
+			reload()
+		case event := <-events:
+			if r.watched(event.Name) {
+				pending = time.After(RELOAD_DEBOUNCE)
+			}
+		case err := <-errs:
+			zap.S().Errorw("Error watching config files",
+				"error", err)
+		case <-pending:
+			pending = nil
+			reload()
+		}
+	}
+}
+
+// watchFiles watches the directories of the current state's files.
+func (r *reloader) watchFiles(watcher *fsnotify.Watcher) {
+	if watcher == nil {
+		return
+	}
+	for _, dir := range watcher.WatchList() {
+		watcher.Remove(dir) //nolint:errcheck
+	}
+	for _, file := range r.State().files {
+		dir := filepath.Dir(file)
+		if err := watcher.Add(dir); err != nil {
+			zap.S().Errorw("Couldn't watch config file",
+				"file", file,
+				"error", err)
+		}
+	}
+}
+
+// watched reports whether a change to the file at path affects the current
+// state. Files are often replaced rather than written in place, e.g. when
+// Kubernetes swaps the ..data symlink of a mounted secret, so changes to
+// those are included.
+func (r *reloader) watched(path string) bool {
+	name := filepath.Base(path)
+	if strings.HasPrefix(name, "..") {
+		return true
+	}
+	for _, file := range r.State().files {
+		if filepath.Base(file) == name {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,102 @@
+package commands
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync/atomic"
+	"syscall"
+	"testing"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/stretchr/testify/require"
+
+	"github.com/onedr0p/exportarr/internal/client"
+	"github.com/onedr0p/exportarr/internal/config"
+)
+
+func TestReloader(t *testing.T) {
+	require := require.New(t)
+
+	var (
+		loads   int
+		fail    bool
+		stopped []context.Context
+	)
+	load := func(ctx context.Context, conf *config.Config) (*state, error) {
+		if fail {
+			return nil, fmt.Errorf("invalid config")
+		}
+		loads++
+		stopped = append(stopped, ctx)
+		return &state{
+			instances: []instance{{app: "sonarr", url: fmt.Sprintf("http://sonarr-%d", loads)}},
+			clients:   client.NewPool(),
+		}, nil
+	}
+	newClient := func() (*client.Client, error) {
+		return client.NewClient("http://sonarr", client.TransportOptions{}, nil, "")
+	}
+	loadConf := func() (*config.Config, error) {
+		return &config.Config{}, nil
+	}
+
+	r, err := newReloader(&config.Config{}, loadConf, load)
+	require.NoError(err)
+	first := r.State()
+	require.Equal("http://sonarr-1", first.instances[0].url)
+	require.Equal(1.0, testutil.ToFloat64(lastReloadSuccessful))
+	firstClient, err := first.clients.Get("sonarr", newClient)
+	require.NoError(err)
+
+	require.NoError(r.Reload())
+	require.Equal("http://sonarr-2", r.State().instances[0].url)
+	require.Error(stopped[0].Err(), "the replaced state must be stopped")
+	require.NoError(stopped[1].Err())
+	require.Equal("http://sonarr-1", first.instances[0].url, "replaced states are left untouched")
+	c, err := first.clients.Get("sonarr", newClient)
+	require.NoError(err)
+	require.NotSame(firstClient, c, "the clients of the replaced state must be dropped")
+
+	successes := testutil.ToFloat64(reloadsTotal.WithLabelValues("success"))
+	fail = true
+	require.Error(r.Reload())
+	require.Equal("http://sonarr-2", r.State().instances[0].url)
+	require.NoError(stopped[1].Err())
+	require.Equal(0.0, testutil.ToFloat64(lastReloadSuccessful))
+	require.Equal(successes, testutil.ToFloat64(reloadsTotal.WithLabelValues("success")))
+	require.GreaterOrEqual(testutil.ToFloat64(reloadsTotal.WithLabelValues("failure")), 1.0)
+}
+
+func TestReloader_Watch(t *testing.T) {
+	require := require.New(t)
+	RELOAD_DEBOUNCE = 10 * time.Millisecond
+	t.Cleanup(func() { RELOAD_DEBOUNCE = time.Second })
+
+	dir := t.TempDir()
+	keyFile := filepath.Join(dir, "api-key")
+	require.NoError(os.WriteFile(keyFile, []byte("first"), 0600))
+
+	var loads atomic.Int32
+	load := func(ctx context.Context, conf *config.Config) (*state, error) {
+		loads.Add(1)
+		return &state{files: []string{keyFile}}, nil
+	}
+	r, err := newReloader(&config.Config{}, func() (*config.Config, error) { return &config.Config{}, nil }, load)
+	require.NoError(err)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go r.Watch(ctx)
+	// Give the watcher time to start
+	time.Sleep(50 * time.Millisecond)
+
+	require.NoError(os.WriteFile(filepath.Join(dir, "unrelated"), []byte("x"), 0600))
+	require.NoError(os.WriteFile(keyFile, []byte("second"), 0600))
+	require.Eventually(func() bool { return loads.Load() == 2 }, 2*time.Second, 10*time.Millisecond)
+
+	require.NoError(syscall.Kill(os.Getpid(), syscall.SIGHUP))
+	require.Eventually(func() bool { return loads.Load() == 3 }, 2*time.Second, 10*time.Millisecond)
+}
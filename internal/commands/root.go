@@ -14,7 +14,10 @@ import (
 	"github.com/spf13/cobra"
 	"go.uber.org/zap"
 	"go.uber.org/zap/zapcore"
+	"golang.org/x/exp/slices"
 
+	base_client "github.com/onedr0p/exportarr/internal/client"
+	base_collector "github.com/onedr0p/exportarr/internal/collector"
 	"github.com/onedr0p/exportarr/internal/config"
 	"github.com/onedr0p/exportarr/internal/handlers"
 )
@@ -31,7 +34,10 @@ var (
 It can export metrics from Radarr, Sonarr, Lidarr, Readarr, Bazarr and Prowlarr.
 More information available at the Github Repo (https://github.com/onedr0p/exportarr)`,
 		PersistentPreRun: func(cmd *cobra.Command, args []string) {
-			conf.App = cmd.Name()
+			// Loaded once the command is known, as it picks the app's section of the config file.
+			initConfig(cmd)
+			initLogger()
+			config.LogDeprecatedEnv(conf.DeprecatedEnv())
 		},
 	}
 )
@@ -49,30 +55,37 @@ func Execute(a AppInfo) error {
 }
 
 func init() {
-	cobra.OnInitialize(initConfig, initLogger)
 	cobra.OnFinalize(finalizeLogger)
 
 	config.RegisterConfigFlags(rootCmd.PersistentFlags())
 }
 
-func initConfig() {
+func initConfig(cmd *cobra.Command) {
 	var err error
-	conf, err = config.LoadConfig(rootCmd.PersistentFlags())
+	conf, err = loadConfig(cmd)
 	if err != nil {
 		fmt.Fprintln(os.Stderr, err)
-		if err := rootCmd.Usage(); err != nil {
+		if err := cmd.Usage(); err != nil {
 			panic(err)
 		}
 		os.Exit(1)
 	}
+}
 
-	if err := conf.Validate(); err != nil {
-		fmt.Fprintln(os.Stderr, err)
-		if err := rootCmd.Usage(); err != nil {
-			panic(err)
-		}
-		os.Exit(1)
+// loadConfig loads and validates the base config of cmd.
+func loadConfig(cmd *cobra.Command) (*config.Config, error) {
+	app, err := commandApp(cmd)
+	if err != nil {
+		return nil, err
+	}
+	c, err := config.LoadConfig(app, cmd.Root().PersistentFlags())
+	if err != nil {
+		return nil, err
 	}
+	if err := c.Validate(); err != nil {
+		return nil, err
+	}
+	return c, nil
 }
 
 func initLogger() {
@@ -109,9 +122,22 @@ func finalizeLogger() {
 	zap.S().Sync() //nolint:errcheck
 }
 
-type registerFunc func(registry prometheus.Registerer)
+// instance is the set of collectors exported for a single app instance.
+// Labels are added to every metric of the instance.
+type instance struct {
+	app        string
+	url        string
+	labels     prometheus.Labels
+	collectors []base_collector.Named
+}
+
+// serveHttp serves the collectors of the instances loaded by load on /metrics.
+// When the loaded state has a probe, single targets can also be scraped through
+// /probe. The state is loaded again whenever the config changes.
+func serveHttp(cmd *cobra.Command, load loadFunc) {
+	r, err := newReloader(conf, func() (*config.Config, error) { return loadConfig(cmd) }, load)
+	UsageOnError(cmd, err)
 
-func serveHttp(fn registerFunc) {
 	var srv http.Server
 
 	idleConnsClosed := make(chan struct{})
@@ -135,31 +161,141 @@ func serveHttp(fn registerFunc) {
 
 	registry := prometheus.NewRegistry()
 	registerAppInfoMetric(registry)
-	fn(registry)
+	registry.MustRegister(base_client.Metrics()...)
+	registry.MustRegister(reloadMetrics()...)
 
 	mux := http.NewServeMux()
-	mux.Handle("/metrics", promhttp.HandlerFor(registry, promhttp.HandlerOpts{}))
+	mux.Handle("/metrics", metricsHandler(registry, r))
+	if r.State().probe != nil {
+		mux.Handle("/probe", handlers.ProbeHandler(func(req *http.Request) ([]prometheus.Collector, error) {
+			return r.State().probe(req)
+		}))
+	}
 	mux.HandleFunc("/", handlers.IndexHandler)
 	mux.HandleFunc("/healthz", handlers.HealthzHandler)
 
+	var web *config.WebConfig
+	if conf.WebConfigFile != "" {
+		var err error
+		web, err = config.LoadWebConfig(conf.WebConfigFile)
+		if err != nil {
+			zap.S().Fatalw("Failed to load web config",
+				"error", err)
+		}
+	}
+
 	zap.S().Infow("Starting HTTP Server",
 		"interface", conf.Interface,
-		"port", conf.Port)
+		"port", conf.Port,
+		"tls", web != nil && web.TLSEnabled())
 	srv.Addr = fmt.Sprintf("%s:%d", conf.Interface, conf.Port)
 
-	wrappedMux := handlers.RecoveryHandler(mux)
+	var wrappedMux http.Handler = mux
+	if web != nil && len(web.BasicAuthUsers) > 0 {
+		var unauthenticated []string
+		if conf.WebHealthzNoAuth {
+			unauthenticated = append(unauthenticated, "/healthz")
+		}
+		wrappedMux = handlers.BasicAuthHandler(web.BasicAuthUsers, unauthenticated, wrappedMux)
+	}
+	wrappedMux = handlers.RecoveryHandler(wrappedMux)
 	wrappedMux = handlers.MetricsHandler(conf, registry, wrappedMux)
 	wrappedMux = handlers.LogHandler(wrappedMux)
 
 	srv.Handler = wrappedMux
 
-	if err := srv.ListenAndServe(); err != http.ErrServerClosed {
+	watchCtx, stopWatching := context.WithCancel(context.Background())
+	defer stopWatching()
+	go r.Watch(watchCtx)
+
+	if web != nil && web.TLSEnabled() {
+		srv.TLSConfig, err = web.TLSConfig()
+		if err != nil {
+			zap.S().Fatalw("Failed to set up TLS",
+				"error", err)
+		}
+		err = srv.ListenAndServeTLS("", "")
+	} else {
+		err = srv.ListenAndServe()
+	}
+	if err != http.ErrServerClosed {
 		zap.S().Fatalw("Failed to Start HTTP Server",
 			"error", err)
 	}
 	<-idleConnsClosed
 }
 
+// metricsHandler serves the exporter's own metrics from self and the collectors
+// of every instance of the current state. Like node_exporter, `collect[]` query
+// parameters restrict the scrape to the named collectors. Collectors are bound to
+// the scrape's context, so their upstream requests are cancelled when the scrape
+// times out.
+func metricsHandler(self prometheus.Gatherer, reloader *reloader) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		s := reloader.State()
+		known := map[string]bool{}
+		for _, i := range s.instances {
+			for _, c := range i.collectors {
+				known[c.Name] = true
+			}
+		}
+		enabled := func(string) bool { return true }
+		if names := r.URL.Query()["collect[]"]; len(names) > 0 {
+			for _, name := range names {
+				if !known[name] {
+					http.Error(w, fmt.Sprintf("unknown collector %s", name), http.StatusBadRequest)
+					return
+				}
+			}
+			enabled = func(name string) bool {
+				return slices.Contains(names, name)
+			}
+		}
+
+		ctx, cancel := handlers.ScrapeContext(r)
+		defer cancel()
+		if s.detect != nil {
+			s.detect(ctx)
+		}
+		collectors := prometheus.NewRegistry()
+		registerInstances(ctx, collectors, s, enabled)
+		promhttp.HandlerFor(prometheus.Gatherers{self, collectors}, promhttp.HandlerOpts{}).ServeHTTP(w, r)
+	})
+}
+
+// registerInstances registers the collectors of the state's instances for which
+// enabled returns true, bound to ctx.
+func registerInstances(ctx context.Context, r prometheus.Registerer, s *state, enabled func(name string) bool) {
+	for _, i := range s.instances {
+		collectors := base_collector.Filter(i.collectors, enabled)
+		if len(collectors) == 0 {
+			continue
+		}
+		ir := prometheus.WrapRegistererWith(i.labels, r)
+		if err := ir.Register(base_collector.NewInstance(ctx, i.app, i.url, collectors, s.conf.CollectorTimeoutFor)); err != nil {
+			zap.S().Errorw("Failed to register collectors",
+				"labels", i.labels,
+				"error", err)
+		}
+	}
+}
+
+// prepareCollectors returns the collectors to register for the instance at url,
+// wrapping them in background pollers running until ctx is done when background
+// refresh is enabled.
+func prepareCollectors(ctx context.Context, conf *config.Config, url string, named []base_collector.Named) []base_collector.Named {
+	if !conf.BackgroundRefresh {
+		return named
+	}
+	ret := make([]base_collector.Named, 0, len(named))
+	for _, n := range named {
+		p := base_collector.NewPollingCollector(n, conf.RefreshIntervalFor(n.Name), conf.CollectorTimeoutFor(n.Name), prometheus.Labels{"url": url})
+		p.Start(ctx)
+		ret = append(ret, base_collector.Named{Name: n.Name, Collector: p})
+	}
+	return ret
+}
+
 func registerAppInfoMetric(registry prometheus.Registerer) {
 	registry.MustRegister(prometheus.NewGaugeFunc(
 		prometheus.GaugeOpts{
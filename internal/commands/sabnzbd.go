@@ -1,13 +1,18 @@
 package commands
 
 import (
+	"context"
+
+	base_client "github.com/onedr0p/exportarr/internal/client"
+	base_collector "github.com/onedr0p/exportarr/internal/collector"
+	base_config "github.com/onedr0p/exportarr/internal/config"
 	"github.com/onedr0p/exportarr/internal/sabnzbd/collector"
 	"github.com/onedr0p/exportarr/internal/sabnzbd/config"
-	"github.com/prometheus/client_golang/prometheus"
 	"github.com/spf13/cobra"
 )
 
 func init() {
+	config.RegisterSabnzbdFlags(sabnzbdCmd.PersistentFlags())
 	rootCmd.AddCommand(sabnzbdCmd)
 }
 
@@ -17,20 +22,29 @@ var sabnzbdCmd = &cobra.Command{
 	Short:   "Prometheus Exporter for Sabnzbd",
 	Long:    "Prometheus Exporter for Sabnzbd.",
 	RunE: func(cmd *cobra.Command, args []string) error {
-		c, err := config.LoadSabnzbdConfig(*conf)
-		if err != nil {
-			return err
-		}
-		if err := c.Validate(); err != nil {
-			return err
-		}
+		serveHttp(cmd, func(ctx context.Context, conf *base_config.Config) (*state, error) {
+			c, err := config.LoadSabnzbdConfig(*conf, cmd.PersistentFlags())
+			if err != nil {
+				return nil, err
+			}
+			if err := c.Validate(); err != nil {
+				return nil, err
+			}
+			c.Clients = base_client.NewPool()
 
-		collector, err := collector.NewSabnzbdCollector(c)
-		if err != nil {
-			return err
-		}
-		serveHttp(func(r prometheus.Registerer) {
-			r.MustRegister(collector)
+			collector, err := collector.NewSabnzbdCollector(c)
+			if err != nil {
+				return nil, err
+			}
+			return &state{
+				instances: []instance{
+					{app: "sabnzbd", url: c.URL, collectors: prepareCollectors(ctx, conf, c.URL, []base_collector.Named{
+						{Name: "sabnzbd", Collector: collector},
+					})},
+				},
+				files:   c.Files(),
+				clients: c.Clients,
+			}, nil
 		})
 		return nil
 	},
@@ -0,0 +1,172 @@
+package commands
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/spf13/cobra"
+	"go.uber.org/zap"
+
+	"github.com/onedr0p/exportarr/internal/arr/config"
+	base_client "github.com/onedr0p/exportarr/internal/client"
+	base_collector "github.com/onedr0p/exportarr/internal/collector"
+	base_config "github.com/onedr0p/exportarr/internal/config"
+	sabnzbd_collector "github.com/onedr0p/exportarr/internal/sabnzbd/collector"
+	sabnzbd_config "github.com/onedr0p/exportarr/internal/sabnzbd/config"
+)
+
+func init() {
+	serveCmd.PersistentFlags().StringP("config", "c", "", "Targets file listing the instances to export")
+	rootCmd.AddCommand(serveCmd)
+}
+
+var serveCmd = &cobra.Command{
+	Use:   "serve",
+	Short: "Prometheus Exporter for multiple instances",
+	Long: `Prometheus Exporter for every instance listed in a targets file.
+Instances can also be scraped one at a time through /probe?target=<url>&app=<app>&module=<name>,
+using the credentials of a module from the targets file.`,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		path, err := cmd.PersistentFlags().GetString("config")
+		if err != nil {
+			return err
+		}
+		if path == "" {
+			UsageOnError(cmd, fmt.Errorf("config is required"))
+		}
+
+		serveHttp(cmd, func(ctx context.Context, conf *base_config.Config) (*state, error) {
+			return loadTargets(ctx, conf, path)
+		})
+		return nil
+	},
+}
+
+// loadTargets loads the targets file at path and builds the instances of its targets.
+func loadTargets(ctx context.Context, conf *base_config.Config, path string) (*state, error) {
+	f, err := config.LoadTargetsFile(*conf, path)
+	if err != nil {
+		return nil, err
+	}
+	for _, err := range f.Errors {
+		zap.S().Errorw("Skipping invalid target",
+			"error", err)
+	}
+	if len(f.Targets) == 0 && len(f.Modules()) == 0 {
+		return nil, fmt.Errorf("no valid targets or modules found in %s", path)
+	}
+
+	clients := base_client.NewPool()
+	var (
+		ds      []*detection
+		configs []*config.ArrConfig
+	)
+	for _, t := range f.Targets {
+		t.Clients = clients
+		if t.App != "sabnzbd" {
+			ds = append(ds, &detection{})
+			configs = append(configs, &t.ArrConfig)
+		}
+	}
+	detectAll(ctx, ds, configs)
+
+	instances := make([]instance, 0, len(f.Targets))
+	for _, t := range f.Targets {
+		collectors, err := targetCollectors(conf, t)
+		if err != nil {
+			zap.S().Errorw("Skipping target",
+				"target", t.Name,
+				"error", err)
+			continue
+		}
+		instances = append(instances, instance{
+			app:        t.App,
+			url:        t.URL,
+			labels:     prometheus.Labels{"instance": t.Name},
+			collectors: prepareCollectors(ctx, conf, t.URL, collectors),
+		})
+		zap.S().Infow("Registered target",
+			"target", t.Name,
+			"app", t.App,
+			"url", t.URL)
+	}
+
+	probed := newDetections()
+	return &state{
+		instances: instances,
+		files:     f.Files(),
+		clients:   clients,
+		detect: func(ctx context.Context) {
+			detectAll(ctx, ds, configs)
+		},
+		probe: func(r *http.Request) ([]prometheus.Collector, error) {
+			q := r.URL.Query()
+			if q.Get("target") == "" {
+				return nil, fmt.Errorf("target parameter is missing")
+			}
+			t, err := f.ProbeTarget(q.Get("app"), q.Get("target"), q.Get("module"))
+			if err != nil {
+				return nil, err
+			}
+			// The client of a probe only lives as long as its request.
+			t.Clients = base_client.NewPool()
+			go func() {
+				<-r.Context().Done()
+				t.Clients.Close()
+			}()
+			if t.App != "sabnzbd" {
+				probed.detect(r.Context(), q.Get("module"), &t.ArrConfig)
+			}
+			collectors, err := targetCollectors(conf, t)
+			if err != nil {
+				return nil, err
+			}
+			return []prometheus.Collector{
+				base_collector.NewInstance(r.Context(), t.App, t.URL, collectors, conf.CollectorTimeoutFor),
+			}, nil
+		},
+	}, nil
+}
+
+// targetCollectors builds the collectors for a single target from the targets
+// file, whose app should have been detected already.
+func targetCollectors(conf *base_config.Config, t *config.Target) ([]base_collector.Named, error) {
+	if t.App != "sabnzbd" {
+		return arrCollectors(&t.ArrConfig), nil
+	}
+	base := *conf
+	base.URL = t.URL
+	base.ApiKey = t.ApiKey
+	base.ApiRootPath = t.ApiRootPath
+	base.DisableSSLVerify = t.DisableSSLVerify
+	base.CAFile = t.CAFile
+	base.ClientCert = t.ClientCert
+	base.ClientKey = t.ClientKey
+	base.TLSServerName = t.TLSServerName
+	base.TLSPinSHA256 = t.TLSPinSHA256
+	base.ProxyURL = t.ProxyURL
+	base.Headers = t.Headers
+	base.OAuth2TokenURL = t.OAuth2TokenURL
+	base.OAuth2ClientID = t.OAuth2ClientID
+	base.OAuth2Secret = t.OAuth2Secret
+	base.OAuth2Scopes = t.OAuth2Scopes
+	if t.XMLConfig != "" {
+		// The url_base of sabnzbd.ini is part of the target's URL already
+		base.ApiRootPath = "/"
+	}
+	c, err := sabnzbd_config.LoadSabnzbdConfig(base, nil)
+	if err != nil {
+		return nil, err
+	}
+	if err := c.Validate(); err != nil {
+		return nil, err
+	}
+	c.Clients = t.Clients
+	collector, err := sabnzbd_collector.NewSabnzbdCollector(c)
+	if err != nil {
+		return nil, err
+	}
+	return []base_collector.Named{{Name: "sabnzbd", Collector: collector}}, nil
+}
@@ -0,0 +1,122 @@
+package config
+
+import (
+	"bufio"
+	"bytes"
+	"net"
+	"net/url"
+	"strings"
+)
+
+// MergeAppConfig merges the settings read from an app's own config file, e.g.
+// Sonarr's config.xml, into the settings of the app. Parsers of these files
+// return the keys:
+//
+//	api-key        the app's API key
+//	url-base       the path the app is served under
+//	target-port    the port the app listens on
+//	ssl-port       the port the app listens on for HTTPS, if any
+//	enable-ssl     whether the app serves HTTPS, as a bool
+//	bind-address   the address the app listens on
+//	auth-method    how users log in to the app, e.g. Forms
+//	instance-name  the name of the app instance
+//
+// The URL of the app is baseURL with the scheme and port the app listens on;
+// when baseURL is empty, the app's bind address is used as its host. Form auth
+// is used when the app requires it and credentials are given.
+func MergeAppConfig(baseURL string) func(src, dest map[string]interface{}) error {
+	return func(src, dest map[string]interface{}) error {
+		str := func(key string) string {
+			s, _ := src[key].(string)
+			return s
+		}
+
+		if str("api-key") != "" {
+			dest["api-key"] = str("api-key")
+		}
+		if str("instance-name") != "" {
+			dest["instance-name"] = str("instance-name")
+		}
+		if username, _ := dest["auth-username"].(string); username != "" && strings.EqualFold(str("auth-method"), "forms") {
+			dest["form-auth"] = true
+		}
+
+		u, err := url.Parse(baseURL)
+		if err != nil {
+			return err
+		}
+
+		// Add or replace scheme and target port, unless the app is reached over a Unix socket
+		if u.Scheme != "unix" {
+			if u.Scheme == "" {
+				u.Scheme = "http"
+			}
+			host := u.Hostname()
+			if baseURL == "" {
+				host = bindHost(str("bind-address"))
+			}
+			port := str("target-port")
+			if enableSsl, _ := src["enable-ssl"].(bool); enableSsl {
+				u.Scheme = "https"
+				if str("ssl-port") != "" {
+					port = str("ssl-port")
+				}
+			}
+			if port == "" {
+				port = u.Port()
+			}
+			u.Host = host
+			if port != "" {
+				u.Host = net.JoinHostPort(host, port)
+			}
+		}
+		u = u.JoinPath(str("url-base"))
+		dest["url"] = u.String()
+		return nil
+	}
+}
+
+// bindHost returns the host an app is reached at when it listens on addr.
+func bindHost(addr string) string {
+	switch addr {
+	case "", "*", "0.0.0.0", "::":
+		return "localhost"
+	default:
+		return addr
+	}
+}
+
+// ParseINI parses the sections of an INI file, like Bazarr's legacy
+// config.ini or SABnzbd's sabnzbd.ini, into maps of their keys. Keys of
+// nested [[sections]] are skipped and quotes around values removed.
+func ParseINI(b []byte) map[string]map[string]string {
+	ret := map[string]map[string]string{}
+	var section map[string]string
+	scanner := bufio.NewScanner(bytes.NewReader(b))
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		switch {
+		case line == "" || strings.HasPrefix(line, "#") || strings.HasPrefix(line, ";"):
+			continue
+		case strings.HasPrefix(line, "[["):
+			section = nil
+		case strings.HasPrefix(line, "["):
+			name := strings.TrimSpace(strings.Trim(line, "[]"))
+			if ret[name] == nil {
+				ret[name] = map[string]string{}
+			}
+			section = ret[name]
+		case section != nil:
+			key, val, ok := strings.Cut(line, "=")
+			if !ok {
+				continue
+			}
+			val = strings.TrimSpace(val)
+			if len(val) >= 2 && (val[0] == '"' || val[0] == '\'') && val[len(val)-1] == val[0] {
+				val = val[1 : len(val)-1]
+			}
+			section[strings.TrimSpace(key)] = val
+		}
+	}
+	return ret
+}
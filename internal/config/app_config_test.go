@@ -0,0 +1,132 @@
+package config
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestMergeAppConfig(t *testing.T) {
+	parameters := []struct {
+		name     string
+		baseURL  string
+		src      map[string]interface{}
+		dest     map[string]interface{}
+		expected map[string]interface{}
+	}{
+		{
+			name:    "port",
+			baseURL: "http://sonarr",
+			src:     map[string]interface{}{"api-key": "abc", "target-port": "8989", "url-base": "/sonarr"},
+			expected: map[string]interface{}{
+				"api-key": "abc",
+				"url":     "http://sonarr:8989/sonarr",
+			},
+		},
+		{
+			name:    "ssl",
+			baseURL: "http://sonarr",
+			src:     map[string]interface{}{"target-port": "8989", "ssl-port": "9898", "enable-ssl": true},
+			expected: map[string]interface{}{
+				"url": "https://sonarr:9898",
+			},
+		},
+		{
+			name:    "missing-port",
+			baseURL: "http://sonarr:8080",
+			src:     map[string]interface{}{"api-key": "abc"},
+			expected: map[string]interface{}{
+				"api-key": "abc",
+				"url":     "http://sonarr:8080",
+			},
+		},
+		{
+			name:    "ssl-on-port",
+			baseURL: "http://sabnzbd",
+			src:     map[string]interface{}{"target-port": "8080", "enable-ssl": true},
+			expected: map[string]interface{}{
+				"url": "https://sabnzbd:8080",
+			},
+		},
+		{
+			name: "bind-address",
+			src:  map[string]interface{}{"target-port": "8989", "bind-address": "::1", "instance-name": "Sonarr 4K"},
+			expected: map[string]interface{}{
+				"instance-name": "Sonarr 4K",
+				"url":           "http://[::1]:8989",
+			},
+		},
+		{
+			name: "any-bind-address",
+			src:  map[string]interface{}{"target-port": "8989", "bind-address": "*"},
+			expected: map[string]interface{}{
+				"url": "http://localhost:8989",
+			},
+		},
+		{
+			name:    "socket",
+			baseURL: "unix:///run/sonarr.sock",
+			src:     map[string]interface{}{"target-port": "8989", "url-base": "/sonarr"},
+			expected: map[string]interface{}{
+				"url": "unix:///run/sonarr.sock/sonarr",
+			},
+		},
+		{
+			name:    "forms-auth",
+			baseURL: "http://sonarr",
+			src:     map[string]interface{}{"target-port": "8989", "auth-method": "Forms"},
+			dest:    map[string]interface{}{"auth-username": "user"},
+			expected: map[string]interface{}{
+				"auth-username": "user",
+				"form-auth":     true,
+				"url":           "http://sonarr:8989",
+			},
+		},
+		{
+			name:    "forms-auth-without-credentials",
+			baseURL: "http://sonarr",
+			src:     map[string]interface{}{"target-port": "8989", "auth-method": "Forms"},
+			expected: map[string]interface{}{
+				"url": "http://sonarr:8989",
+			},
+		},
+	}
+	for _, p := range parameters {
+		t.Run(p.name, func(t *testing.T) {
+			require := require.New(t)
+			dest := p.dest
+			if dest == nil {
+				dest = map[string]interface{}{}
+			}
+			require.NoError(MergeAppConfig(p.baseURL)(p.src, dest))
+			require.Equal(p.expected, dest)
+		})
+	}
+}
+
+func TestParseINI(t *testing.T) {
+	require := require.New(t)
+	sections := ParseINI([]byte(`
+# comment
+__version__ = 19
+[misc]
+host = ::
+port = 8080
+api_key = "abcdef0123456789"
+url_base = /sabnzbd
+; comment
+[servers]
+[[news.example.com]]
+port = 563
+[misc]
+https_port = 9090
+`))
+	require.Equal(map[string]string{
+		"host":       "::",
+		"port":       "8080",
+		"api_key":    "abcdef0123456789",
+		"url_base":   "/sabnzbd",
+		"https_port": "9090",
+	}, sections["misc"])
+	require.Empty(sections["servers"], "Keys of nested sections should be skipped")
+}
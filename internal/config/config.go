@@ -3,97 +3,231 @@ package config
 import (
 	"fmt"
 	"os"
+	"strconv"
 	"strings"
+	"time"
 
 	"github.com/gookit/validate"
 	"github.com/knadh/koanf/providers/confmap"
-	"github.com/knadh/koanf/providers/env"
 	"github.com/knadh/koanf/providers/posflag"
 	"github.com/knadh/koanf/v2"
 	flag "github.com/spf13/pflag"
 	"go.uber.org/zap/zapcore"
 	"golang.org/x/exp/slices"
+
+	"github.com/onedr0p/exportarr/internal/client"
 )
 
 func RegisterConfigFlags(flags *flag.FlagSet) {
+	flags.String("config-file", "", "YAML or TOML file with the settings of exportarr and each app")
+	flags.Bool("disable-legacy-env", false, "Only read environment variables prefixed with "+ENV_PREFIX)
 	flags.StringP("log-level", "l", "info", "Log level (debug, info, warn, error, fatal, panic)")
 	flags.String("log-format", "console", "Log format (console, json)")
 	flags.StringP("url", "u", "", "URL to *arr instance")
 	flags.StringP("api-key", "a", "", "API Key for *arr instance")
 	flags.String("api-key-file", "", "File containing API Key for *arr instance")
+	flags.String("vault-addr", "", "Address of the Vault server for vault: secrets (default $VAULT_ADDR)")
+	flags.String("vault-token", "", "Token for vault: secrets (default $VAULT_TOKEN)")
+	flags.String("vault-token-file", "", "File containing the token for vault: secrets")
 	flags.String("api-root-path", "", "Root path for api calls.")
 	flags.Bool("disable-ssl-verify", false, "Disable SSL verification")
+	flags.String("ca-file", "", "PEM file with CAs trusted to verify the app's certificate, besides the system ones")
+	flags.String("client-cert", "", "PEM client certificate presented to the app for mTLS")
+	flags.String("client-key", "", "PEM key of the client certificate")
+	flags.String("tls-server-name", "", "Server name verified in the app's certificate, instead of the URL's host")
+	flags.StringSlice("tls-pin-sha256", nil, "SHA-256 fingerprints, one of which the app's certificate chain must contain")
+	flags.String("proxy-url", "", "HTTP(S) or SOCKS5 proxy for requests to the app, e.g. socks5://proxy:1080 (default from HTTP_PROXY, HTTPS_PROXY and NO_PROXY)")
+	flags.StringToString("headers", nil, "Headers added to every request to the app, e.g. for an SSO proxy: CF-Access-Client-Id=id")
+	flags.String("oauth2-token-url", "", "Token endpoint to fetch a bearer token from with the OAuth2 client credentials grant")
+	flags.String("oauth2-client-id", "", "OAuth2 client ID")
+	flags.String("oauth2-client-secret", "", "OAuth2 client secret")
+	flags.String("oauth2-client-secret-file", "", "File containing the OAuth2 client secret")
+	flags.StringSlice("oauth2-scopes", nil, "Scopes requested with the OAuth2 token")
 	flags.StringP("interface", "i", "", "IP address to listen on")
 	flags.IntP("port", "p", 0, "Port to listen on")
+	flags.String("web-config-file", "", "Web config file setting up TLS and basic auth for the exporter, in Prometheus exporter-toolkit format")
+	flags.Bool("web-healthz-no-auth", false, "Serve /healthz without basic auth, e.g. for liveness probes")
+	flags.Bool("background-refresh", false, "Refresh collectors in the background and serve the latest results on scrape")
+	flags.Duration("refresh-interval", 5*time.Minute, "Default interval between background refreshes")
+	flags.StringToString("refresh-intervals", nil, "Background refresh interval per collector, e.g. queue=30s,sonarr=15m")
+	flags.Duration("collector-timeout", 0, "Default timeout for a collector's upstream requests, 0 for none")
+	flags.StringToString("collector-timeouts", nil, "Timeout per collector, e.g. history=30s,queue=5s")
+	flags.Int("retry-attempts", 3, "Attempts for each request to the app, including the first. 0 or 1 disables retries")
+	flags.Duration("retry-backoff", 250*time.Millisecond, "Delay before retrying a failed request, doubled for each further retry")
+	flags.Duration("retry-max-backoff", 5*time.Second, "Maximum delay between retries")
+	flags.Int("circuit-breaker-threshold", 0, "Consecutive failed requests after which the app isn't called for the cool-down, 0 to disable")
+	flags.Duration("circuit-breaker-cooldown", time.Minute, "How long to stop calling the app once the circuit breaker opens")
 }
 
 type Config struct {
-	App              string `koanf:"-"`
-	LogLevel         string `koanf:"log-level" validate:"ValidateLogLevel"`
-	LogFormat        string `koanf:"log-format" validate:"in:console,json"`
-	URL              string `koanf:"url"`
-	ApiKey           string `koanf:"api-key"`
-	ApiKeyFile       string `koanf:"api-key-file"`
-	ApiRootPath      string `koanf:"api-root-path"`
-	Port             int    `koanf:"port" validate:"required"`
-	Interface        string `koanf:"interface" validate:"required|ip"`
-	DisableSSLVerify bool   `koanf:"disable-ssl-verify"`
-	k                *koanf.Koanf
-}
-
-func LoadConfig(flags *flag.FlagSet) (*Config, error) {
+	App               string                   `koanf:"-"`
+	ConfigFile        string                   `koanf:"config-file"`
+	DisableLegacyEnv  bool                     `koanf:"disable-legacy-env"`
+	LogLevel          string                   `koanf:"log-level" validate:"ValidateLogLevel"`
+	LogFormat         string                   `koanf:"log-format" validate:"in:console,json"`
+	URL               string                   `koanf:"url"`
+	ApiKey            string                   `koanf:"api-key"`
+	ApiKeyFile        string                   `koanf:"api-key-file"`
+	ApiRootPath       string                   `koanf:"api-root-path"`
+	VaultAddr         string                   `koanf:"vault-addr"`
+	VaultToken        string                   `koanf:"vault-token"`
+	VaultTokenFile    string                   `koanf:"vault-token-file"`
+	Port              int                      `koanf:"port" validate:"required"`
+	Interface         string                   `koanf:"interface" validate:"required|ip"`
+	DisableSSLVerify  bool                     `koanf:"disable-ssl-verify"`
+	CAFile            string                   `koanf:"ca-file"`
+	ClientCert        string                   `koanf:"client-cert"`
+	ClientKey         string                   `koanf:"client-key"`
+	TLSServerName     string                   `koanf:"tls-server-name"`
+	TLSPinSHA256      []string                 `koanf:"tls-pin-sha256"`
+	ProxyURL          string                   `koanf:"proxy-url"`
+	Headers           map[string]string        `koanf:"headers"`
+	OAuth2TokenURL    string                   `koanf:"oauth2-token-url" validate:"url"`
+	OAuth2ClientID    string                   `koanf:"oauth2-client-id"`
+	OAuth2Secret      string                   `koanf:"oauth2-client-secret"`
+	OAuth2SecretFile  string                   `koanf:"oauth2-client-secret-file"`
+	OAuth2Scopes      []string                 `koanf:"oauth2-scopes"`
+	WebConfigFile     string                   `koanf:"web-config-file"`
+	WebHealthzNoAuth  bool                     `koanf:"web-healthz-no-auth"`
+	BackgroundRefresh bool                     `koanf:"background-refresh"`
+	RefreshInterval   time.Duration            `koanf:"refresh-interval"`
+	RefreshIntervals  map[string]time.Duration `koanf:"refresh-intervals"`
+	CollectorTimeout  time.Duration            `koanf:"collector-timeout"`
+	CollectorTimeouts map[string]time.Duration `koanf:"collector-timeouts"`
+	RetryAttempts     int                      `koanf:"retry-attempts"`
+	RetryBackoff      time.Duration            `koanf:"retry-backoff"`
+	RetryMaxBackoff   time.Duration            `koanf:"retry-max-backoff"`
+	BreakerThreshold  int                      `koanf:"circuit-breaker-threshold"`
+	BreakerCooldown   time.Duration            `koanf:"circuit-breaker-cooldown"`
+	k                 *koanf.Koanf
+	file              *File
+	deprecatedEnv     []DeprecatedEnv
+}
+
+// LoadConfig loads the settings of app from its defaults, the config file, the
+// environment and flags, each overriding the previous.
+func LoadConfig(app string, flags *flag.FlagSet) (*Config, error) {
 	k := koanf.New(".")
 
 	// Defaults
 	err := k.Load(confmap.Provider(map[string]interface{}{
-		"log-level":        "info",
-		"log-format":       "console",
-		"api-version":      "v3",
-		"port":             "8081",
-		"interface":        "0.0.0.0",
-		"api-root-path":    "/", 
+		"log-level":                 "info",
+		"log-format":                "console",
+		"api-version":               "v3",
+		"port":                      "8081",
+		"interface":                 "0.0.0.0",
+		"api-root-path":             "/",
+		"refresh-interval":          "5m",
+		"retry-attempts":            3,
+		"retry-backoff":             "250ms",
+		"retry-max-backoff":         "5s",
+		"circuit-breaker-threshold": 0,
+		"circuit-breaker-cooldown":  "1m",
 	}, "."), nil)
 	if err != nil {
 		return nil, err
 	}
 
-	// Environment
-	err = k.Load(env.Provider("", ".", func(s string) string {
-		s = strings.ToLower(s)
-		s = strings.Replace(s, "__", ".", -1)
-		s = strings.Replace(s, "_", "-", -1)
-		return backwardsCompatibilityTransforms(s)
-	}), nil)
+	// Environment, loaded on its own first to know which settings of the config file it overrides
+	disableLegacyEnv, _ := strconv.ParseBool(os.Getenv(ENV_PREFIX + "DISABLE_LEGACY_ENV"))
+	if flags.Changed("disable-legacy-env") {
+		disableLegacyEnv, _ = flags.GetBool("disable-legacy-env")
+	}
+	envK, deprecatedEnv, err := LoadEnv(EnvOptions{
+		App:       app,
+		Keys:      legacyKeys(),
+		Legacy:    !disableLegacyEnv,
+		Transform: backwardsCompatibilityTransforms,
+	})
 	if err != nil {
 		return nil, err
 	}
 
+	// Config File
+	configFile := envK.String("config-file")
+	if flags.Changed("config-file") {
+		configFile, _ = flags.GetString("config-file")
+	}
+	var f *File
+	if configFile != "" {
+		f, err = LoadFile(configFile, app)
+		if err != nil {
+			return nil, err
+		}
+		if err := k.Merge(f.Koanf()); err != nil {
+			return nil, err
+		}
+		f.Shadow(envK.Keys()...)
+		f.ShadowFlags(flags)
+	}
+
+	if err := k.Merge(envK); err != nil {
+		return nil, err
+	}
+
 	// Flags
 	if err = k.Load(posflag.Provider(flags, ".", k), nil); err != nil {
 		return nil, err
 	}
 
-	// API Key File
-	apiKeyFile := k.String("api-key-file")
-	if apiKeyFile != "" {
-		data, err := os.ReadFile(apiKeyFile)
-		if err != nil {
-			return nil, fmt.Errorf("Couldn't Read API Key file %w", err)
+	// Secrets, the vault token first as vault: secrets need it
+	if err := (SecretSource{}).Resolve(k, "vault-token"); err != nil {
+		return nil, f.Explain(err)
+	}
+	secrets := SecretSource{
+		VaultAddr:  k.String("vault-addr"),
+		VaultToken: k.String("vault-token"),
+	}
+	for _, key := range []string{"api-key", "oauth2-client-secret"} {
+		if err := secrets.Resolve(k, key); err != nil {
+			return nil, f.Explain(err)
 		}
+	}
 
-		if err := k.Set("api-key", string(data)); err != nil {
-			return nil, fmt.Errorf("Couldn't merge api-key into config: %w", err)
+	// Per collector maps from the environment are a single comma separated string
+	for _, key := range []string{"refresh-intervals", "collector-timeouts", "headers"} {
+		val, ok := k.Get(key).(string)
+		if !ok {
+			continue
+		}
+		m, err := parseStringToString(val)
+		if err != nil {
+			return nil, fmt.Errorf("Couldn't parse %s: %w", key, err)
+		}
+		k.Delete(key)
+		if err := k.Set(key, m); err != nil {
+			return nil, fmt.Errorf("Couldn't merge %s into config: %w", key, err)
 		}
 	}
 
 	var out Config
 	if err := k.Unmarshal("", &out); err != nil {
-		return nil, err
+		return nil, f.Explain(err)
+	}
+	out.TLSPinSHA256 = SplitList(out.TLSPinSHA256)
+	out.OAuth2Scopes = SplitList(out.OAuth2Scopes)
+	if err := secrets.ResolveValues("headers", out.Headers); err != nil {
+		return nil, f.Explain(err)
 	}
+	out.App = app
 	out.k = k
+	out.file = f
+	out.deprecatedEnv = deprecatedEnv
 	return &out, nil
 }
 
+// legacyKeys returns the settings read from legacy environment variables. VAULT_ADDR
+// and VAULT_TOKEN are Vault's own variables, used as defaults by the vault source.
+func legacyKeys() []string {
+	var ret []string
+	for _, key := range Keys(Config{}) {
+		if !strings.HasPrefix(key, "vault-") {
+			ret = append(ret, key)
+		}
+	}
+	return ret
+}
+
 // ValidateLogLevel validates that the log level is one of the valid log levels
 // gookit/Validate is pretty opinionated, and requires that this is not a pointer method.
 func (c Config) ValidateLogLevel(val string) bool {
@@ -104,12 +238,120 @@ func (c Config) ValidateLogLevel(val string) bool {
 	return slices.Contains(validLogLevels, val)
 
 }
+
+// File returns the config file the settings were loaded from, nil when there is none.
+func (c *Config) File() *File {
+	return c.file
+}
+
+// Files returns the files the settings were read from, which are watched for changes.
+func (c *Config) Files() []string {
+	var ret []string
+	for _, file := range []string{c.ConfigFile, c.ApiKeyFile, c.VaultTokenFile, c.OAuth2SecretFile} {
+		if file != "" {
+			ret = append(ret, file)
+		}
+	}
+	return append(ret, c.TLS().Files()...)
+}
+
+// TLS returns the settings verifying the app's certificate.
+func (c *Config) TLS() TLSConfig {
+	return TLSConfig{
+		InsecureSkipVerify: c.DisableSSLVerify,
+		CAFile:             c.CAFile,
+		CertFile:           c.ClientCert,
+		KeyFile:            c.ClientKey,
+		ServerName:         c.TLSServerName,
+		PinnedSHA256:       c.TLSPinSHA256,
+	}
+}
+
+// RetryPolicy returns how requests to the app are retried.
+func (c *Config) RetryPolicy() *client.RetryPolicy {
+	return &client.RetryPolicy{
+		Attempts:         c.RetryAttempts,
+		Backoff:          c.RetryBackoff,
+		MaxBackoff:       c.RetryMaxBackoff,
+		BreakerThreshold: c.BreakerThreshold,
+		BreakerCooldown:  c.BreakerCooldown,
+	}
+}
+
+// Secrets returns the source of secrets set by the config.
+func (c *Config) Secrets() SecretSource {
+	return SecretSource{
+		VaultAddr:  c.VaultAddr,
+		VaultToken: c.VaultToken,
+	}
+}
+
+// DeprecatedEnv returns the legacy environment variables the settings were read from.
+func (c *Config) DeprecatedEnv() []DeprecatedEnv {
+	return c.deprecatedEnv
+}
+
+// Validate checks the settings, naming the fields of the config file which set invalid ones.
 func (c *Config) Validate() error {
+	return c.file.Explain(c.validate())
+}
+
+func (c *Config) validate() error {
 	v := validate.Struct(c)
 	if !v.Validate() {
 		return v.Errors
 	}
-	return nil
+	if c.BackgroundRefresh && c.RefreshInterval <= 0 {
+		return fmt.Errorf("refresh-interval must be greater than zero")
+	}
+	for name, interval := range c.RefreshIntervals {
+		if interval <= 0 {
+			return fmt.Errorf("refresh-intervals: interval for %s must be greater than zero", name)
+		}
+	}
+	if c.CollectorTimeout < 0 {
+		return fmt.Errorf("collector-timeout must not be negative")
+	}
+	for name, timeout := range c.CollectorTimeouts {
+		if timeout <= 0 {
+			return fmt.Errorf("collector-timeouts: timeout for %s must be greater than zero", name)
+		}
+	}
+	if c.RetryAttempts < 0 {
+		return fmt.Errorf("retry-attempts must not be negative")
+	}
+	if c.RetryBackoff < 0 || c.RetryMaxBackoff < c.RetryBackoff {
+		return fmt.Errorf("retry-backoff must not be negative or greater than retry-max-backoff")
+	}
+	if c.BreakerThreshold < 0 {
+		return fmt.Errorf("circuit-breaker-threshold must not be negative")
+	}
+	if c.BreakerThreshold > 0 && c.BreakerCooldown <= 0 {
+		return fmt.Errorf("circuit-breaker-cooldown must be greater than zero")
+	}
+	if c.OAuth2TokenURL != "" && c.OAuth2ClientID == "" {
+		return fmt.Errorf("oauth2-client-id is required when oauth2-token-url is set")
+	}
+	if err := ValidateUpstream(c.URL, c.ProxyURL); err != nil {
+		return err
+	}
+	return c.TLS().Validate()
+}
+
+// RefreshIntervalFor returns the background refresh interval of the named collector.
+func (c *Config) RefreshIntervalFor(name string) time.Duration {
+	if interval, ok := c.RefreshIntervals[name]; ok {
+		return interval
+	}
+	return c.RefreshInterval
+}
+
+// CollectorTimeoutFor returns the timeout of the named collector, 0 when it has none.
+func (c *Config) CollectorTimeoutFor(name string) time.Duration {
+	if timeout, ok := c.CollectorTimeouts[name]; ok {
+		return timeout
+	}
+	return c.CollectorTimeout
 }
 
 func (c Config) Messages() map[string]string {
@@ -121,17 +363,76 @@ func (c Config) Messages() map[string]string {
 
 func (c Config) Translates() map[string]string {
 	return validate.MS{
-		"LogLevel":         "log-level",
-		"LogFormat":        "log-format",
-		"URL":              "url",
-		"ApiKey":           "api-key",
-		"ApiKeyFile":       "api-key-file",
-		"ApiVersion":       "api-version",
-		"ApiRootPath":      "api-root-path",
-		"Port":             "port",
-		"Interface":        "interface",
-		"DisableSSLVerify": "disable-ssl-verify",
+		"ConfigFile":        "config-file",
+		"DisableLegacyEnv":  "disable-legacy-env",
+		"LogLevel":          "log-level",
+		"LogFormat":         "log-format",
+		"URL":               "url",
+		"ApiKey":            "api-key",
+		"ApiKeyFile":        "api-key-file",
+		"ApiVersion":        "api-version",
+		"ApiRootPath":       "api-root-path",
+		"VaultAddr":         "vault-addr",
+		"VaultToken":        "vault-token",
+		"VaultTokenFile":    "vault-token-file",
+		"Port":              "port",
+		"Interface":         "interface",
+		"DisableSSLVerify":  "disable-ssl-verify",
+		"CAFile":            "ca-file",
+		"ClientCert":        "client-cert",
+		"ClientKey":         "client-key",
+		"TLSServerName":     "tls-server-name",
+		"TLSPinSHA256":      "tls-pin-sha256",
+		"ProxyURL":          "proxy-url",
+		"Headers":           "headers",
+		"OAuth2TokenURL":    "oauth2-token-url",
+		"OAuth2ClientID":    "oauth2-client-id",
+		"OAuth2Secret":      "oauth2-client-secret",
+		"OAuth2SecretFile":  "oauth2-client-secret-file",
+		"OAuth2Scopes":      "oauth2-scopes",
+		"WebConfigFile":     "web-config-file",
+		"WebHealthzNoAuth":  "web-healthz-no-auth",
+		"BackgroundRefresh": "background-refresh",
+		"RefreshInterval":   "refresh-interval",
+		"RefreshIntervals":  "refresh-intervals",
+		"CollectorTimeout":  "collector-timeout",
+		"CollectorTimeouts": "collector-timeouts",
+		"RetryAttempts":     "retry-attempts",
+		"RetryBackoff":      "retry-backoff",
+		"RetryMaxBackoff":   "retry-max-backoff",
+		"BreakerThreshold":  "circuit-breaker-threshold",
+		"BreakerCooldown":   "circuit-breaker-cooldown",
+	}
+}
+
+// parseStringToString parses "a=1,b=2" the same way as pflag's StringToString flags.
+func parseStringToString(s string) (map[string]string, error) {
+	ret := map[string]string{}
+	if strings.TrimSpace(s) == "" {
+		return ret, nil
+	}
+	for _, pair := range strings.Split(s, ",") {
+		kv := strings.SplitN(pair, "=", 2)
+		if len(kv) != 2 {
+			return nil, fmt.Errorf("%s must be formatted as key=value", pair)
+		}
+		ret[strings.TrimSpace(kv[0])] = strings.TrimSpace(kv[1])
+	}
+	return ret, nil
+}
+
+// SplitList splits comma separated entries, as lists from the environment
+// arrive as a single string.
+func SplitList(in []string) []string {
+	var ret []string
+	for _, entry := range in {
+		for _, s := range strings.Split(entry, ",") {
+			if s = strings.TrimSpace(s); s != "" {
+				ret = append(ret, s)
+			}
+		}
 	}
+	return ret
 }
 
 // Remove in v2.0.0
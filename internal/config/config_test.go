@@ -2,6 +2,7 @@ package config
 
 import (
 	"testing"
+	"time"
 
 	"github.com/spf13/pflag"
 	"github.com/stretchr/testify/require"
@@ -15,7 +16,7 @@ func testFlagSet() *pflag.FlagSet {
 func TestLoadConfig_Defaults(t *testing.T) {
 	require := require.New(t)
 
-	config, err := LoadConfig(&pflag.FlagSet{})
+	config, err := LoadConfig("", &pflag.FlagSet{})
 	require.NoError(err)
 	require.Equal("info", config.LogLevel)
 	require.Equal("console", config.LogFormat)
@@ -33,7 +34,7 @@ func TestLoadConfig_Flags(t *testing.T) {
 	flags.Set("disable-ssl-verify", "true")
 
 	require := require.New(t)
-	config, err := LoadConfig(flags)
+	config, err := LoadConfig("", flags)
 	require.NoError(err)
 
 	require.Equal("debug", config.LogLevel)
@@ -44,7 +45,7 @@ func TestLoadConfig_Flags(t *testing.T) {
 	require.True(config.DisableSSLVerify)
 
 	flags.Set("form-auth", "false")
-	_, err = LoadConfig(flags)
+	_, err = LoadConfig("", flags)
 	require.NoError(err)
 }
 
@@ -58,7 +59,7 @@ func TestLoadConfig_Environment(t *testing.T) {
 	t.Setenv("INTERFACE", "1.2.3.4")
 	t.Setenv("DISABLE_SSL_VERIFY", "true")
 
-	config, err := LoadConfig(&pflag.FlagSet{})
+	config, err := LoadConfig("", &pflag.FlagSet{})
 	require.NoError(err)
 
 	require.Equal("http://localhost:8989", config.URL)
@@ -77,7 +78,7 @@ func TestLoadConfig_PartialEnvironment(t *testing.T) {
 	t.Setenv("PORT", "1234")
 
 	require := require.New(t)
-	config, err := LoadConfig(flags)
+	config, err := LoadConfig("", flags)
 	require.NoError(err)
 
 	// Env
@@ -103,7 +104,7 @@ func TestLoadConfig_BackwardsCompatibility_ApiKeyFile(t *testing.T) {
 	t.Setenv("BASIC_AUTH_USERNAME", "user")
 	t.Setenv("BASIC_AUTH_PASSWORD", "pass")
 
-	config, err := LoadConfig(&pflag.FlagSet{})
+	config, err := LoadConfig("", &pflag.FlagSet{})
 	require.NoError(err)
 
 	require.Equal("abcdef0123456789abcdef0123456783", config.ApiKey)
@@ -117,7 +118,7 @@ func TestLoadConfig_BackwardsCompatibility_ApiKey(t *testing.T) {
 	t.Setenv("APIKEY", "abcdef0123456789abcdef0123456780")
 	t.Setenv("PORT", "1234")
 
-	config, err := LoadConfig(&pflag.FlagSet{})
+	config, err := LoadConfig("", &pflag.FlagSet{})
 	require.NoError(err)
 
 	require.Equal("abcdef0123456789abcdef0123456780", config.ApiKey)
@@ -128,7 +129,7 @@ func TestLoadConfig_ApiKeyFile(t *testing.T) {
 	flags.Set("api-key-file", "test_fixtures/api_key")
 
 	require := require.New(t)
-	config, err := LoadConfig(flags)
+	config, err := LoadConfig("", flags)
 	require.NoError(err)
 
 	require.Equal("abcdef0123456789abcdef0123456783", config.ApiKey)
@@ -140,22 +141,116 @@ func TestLoadConfig_OverrideOrder(t *testing.T) {
 	flags := testFlagSet()
 
 	t.Setenv("API_KEY", "abcdef0123456789abcdef0123456781")
-	config, err := LoadConfig(flags)
+	config, err := LoadConfig("", flags)
 	require.NoError(err)
 	require.Equal("abcdef0123456789abcdef0123456781", config.ApiKey)
 
 	flags.Set("api-key", "abcdef0123456789abcdef0123456780")
 
-	config, err = LoadConfig(flags)
+	config, err = LoadConfig("", flags)
 	require.NoError(err)
 	require.Equal("abcdef0123456789abcdef0123456780", config.ApiKey)
 
 	flags.Set("api-key-file", "test_fixtures/api_key")
-	config, err = LoadConfig(flags)
+	config, err = LoadConfig("", flags)
 	require.NoError(err)
 	require.Equal("abcdef0123456789abcdef0123456783", config.ApiKey)
 }
 
+func TestLoadConfig_RefreshIntervals(t *testing.T) {
+	require := require.New(t)
+
+	config, err := LoadConfig("", &pflag.FlagSet{})
+	require.NoError(err)
+	require.False(config.BackgroundRefresh)
+	require.Equal(5*time.Minute, config.RefreshIntervalFor("queue"))
+
+	t.Setenv("BACKGROUND_REFRESH", "true")
+	t.Setenv("REFRESH_INTERVAL", "10m")
+	t.Setenv("REFRESH_INTERVALS", "queue=30s, sonarr=15m")
+	config, err = LoadConfig("", &pflag.FlagSet{})
+	require.NoError(err)
+	require.True(config.BackgroundRefresh)
+	require.Equal(30*time.Second, config.RefreshIntervalFor("queue"))
+	require.Equal(15*time.Minute, config.RefreshIntervalFor("sonarr"))
+	require.Equal(10*time.Minute, config.RefreshIntervalFor("history"))
+
+	flags := testFlagSet()
+	flags.Set("refresh-intervals", "queue=1m")
+	config, err = LoadConfig("", flags)
+	require.NoError(err)
+	require.Equal(time.Minute, config.RefreshIntervalFor("queue"))
+	require.Equal(10*time.Minute, config.RefreshIntervalFor("sonarr"))
+
+	t.Setenv("REFRESH_INTERVALS", "queue")
+	_, err = LoadConfig("", &pflag.FlagSet{})
+	require.Error(err)
+}
+
+func TestLoadConfig_CollectorTimeouts(t *testing.T) {
+	require := require.New(t)
+
+	config, err := LoadConfig("", &pflag.FlagSet{})
+	require.NoError(err)
+	require.Zero(config.CollectorTimeoutFor("queue"))
+
+	t.Setenv("COLLECTOR_TIMEOUT", "10s")
+	t.Setenv("COLLECTOR_TIMEOUTS", "history=30s")
+	config, err = LoadConfig("", &pflag.FlagSet{})
+	require.NoError(err)
+	require.Equal(30*time.Second, config.CollectorTimeoutFor("history"))
+	require.Equal(10*time.Second, config.CollectorTimeoutFor("queue"))
+
+	flags := testFlagSet()
+	flags.Set("collector-timeouts", "queue=5s")
+	config, err = LoadConfig("", flags)
+	require.NoError(err)
+	require.Equal(5*time.Second, config.CollectorTimeoutFor("queue"))
+	require.Equal(10*time.Second, config.CollectorTimeoutFor("history"))
+}
+
+func TestLoadConfig_TLS(t *testing.T) {
+	require := require.New(t)
+
+	t.Setenv("EXPORTARR_CA_FILE", "ca.pem")
+	t.Setenv("EXPORTARR_TLS_PIN_SHA256", "ab:cd, ef:01")
+	flags := testFlagSet()
+	flags.Set("tls-server-name", "sonarr.internal")
+	config, err := LoadConfig("", flags)
+	require.NoError(err)
+
+	tls := config.TLS()
+	require.Equal("ca.pem", tls.CAFile)
+	require.Equal("sonarr.internal", tls.ServerName)
+	require.Equal([]string{"ab:cd", "ef:01"}, tls.PinnedSHA256)
+	require.Contains(config.Files(), "ca.pem", "The CA file should be watched for changes")
+}
+
+func TestLoadConfig_ProxyAuth(t *testing.T) {
+	require := require.New(t)
+
+	t.Setenv("EXPORTARR_HEADERS", "CF-Access-Client-Id=id, CF-Access-Client-Secret=exec:echo service-token")
+	t.Setenv("EXPORTARR_OAUTH2_TOKEN_URL", "https://auth.example.com/token")
+	t.Setenv("EXPORTARR_OAUTH2_CLIENT_ID", "exportarr")
+	t.Setenv("EXPORTARR_OAUTH2_CLIENT_SECRET_FILE", "test_fixtures/api_key")
+	t.Setenv("EXPORTARR_OAUTH2_SCOPES", "read,write")
+	config, err := LoadConfig("", &pflag.FlagSet{})
+	require.NoError(err)
+
+	require.Equal(map[string]string{
+		"CF-Access-Client-Id":     "id",
+		"CF-Access-Client-Secret": "service-token",
+	}, config.Headers, "Header values should be resolved as secrets")
+	require.Equal("abcdef0123456789abcdef0123456783", config.OAuth2Secret)
+	require.Equal([]string{"read", "write"}, config.OAuth2Scopes)
+	require.Contains(config.Files(), "test_fixtures/api_key")
+
+	t.Setenv("EXPORTARR_OAUTH2_CLIENT_ID", "")
+	config, err = LoadConfig("", &pflag.FlagSet{})
+	require.NoError(err)
+	require.ErrorContains(config.Validate(), "oauth2-client-id")
+}
+
 func TestValidate(t *testing.T) {
 	parameters := []struct {
 		name        string
@@ -205,6 +300,31 @@ func TestValidate(t *testing.T) {
 			},
 			shouldError: true,
 		},
+		{
+			name: "bad-retry-backoff",
+			config: &Config{
+				LogLevel:        "debug",
+				URL:             "http://localhost",
+				ApiKey:          "abcdef0123456789abcdef0123456789",
+				Port:            1234,
+				Interface:       "0.0.0.0",
+				RetryBackoff:    10 * time.Second,
+				RetryMaxBackoff: time.Second,
+			},
+			shouldError: true,
+		},
+		{
+			name: "missing-circuit-breaker-cooldown",
+			config: &Config{
+				LogLevel:         "debug",
+				URL:              "http://localhost",
+				ApiKey:           "abcdef0123456789abcdef0123456789",
+				Port:             1234,
+				Interface:        "0.0.0.0",
+				BreakerThreshold: 5,
+			},
+			shouldError: true,
+		},
 	}
 
 	for _, p := range parameters {
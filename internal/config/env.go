@@ -0,0 +1,137 @@
+package config
+
+import (
+	"os"
+	"reflect"
+	"strings"
+	"sync"
+
+	"github.com/knadh/koanf/providers/confmap"
+	"github.com/knadh/koanf/v2"
+	"go.uber.org/zap"
+	"golang.org/x/exp/slices"
+)
+
+// ENV_PREFIX is the prefix of the environment variables read by exportarr,
+// e.g. EXPORTARR_LOG_LEVEL. Settings for a single app are nested under its
+// name, e.g. EXPORTARR_SONARR__URL.
+const ENV_PREFIX = "EXPORTARR_"
+
+// DeprecatedEnv is a legacy environment variable without the EXPORTARR_ prefix which was read.
+type DeprecatedEnv struct {
+	Name        string
+	Replacement string
+}
+
+// EnvOptions controls which environment variables LoadEnv reads.
+type EnvOptions struct {
+	App       string
+	Keys      []string            // Legacy variables are only read for these settings
+	Legacy    bool                // Also read legacy variables without the prefix
+	Transform func(string) string // Renames settings, e.g. for backwards compatibility
+}
+
+// LoadEnv reads settings from the environment. Settings nested under the app
+// override the top-level ones, as do prefixed variables the legacy ones.
+// Legacy variables are only read when they name a known setting, so generic variables
+// like PATH don't leak into the config; those read are returned to warn about.
+func LoadEnv(opts EnvOptions) (*koanf.Koanf, []DeprecatedEnv, error) {
+	legacy := map[string]interface{}{}
+	prefixed := map[string]interface{}{}
+	nested := map[string]interface{}{}
+	var deprecated []DeprecatedEnv
+
+	for _, kv := range os.Environ() {
+		name, val, _ := strings.Cut(kv, "=")
+		if strings.HasPrefix(name, ENV_PREFIX) {
+			key := envKey(strings.TrimPrefix(name, ENV_PREFIX), opts.Transform)
+			app, setting, found := strings.Cut(key, ".")
+			switch {
+			case found && slices.Contains(Apps, app) && app != opts.App:
+				continue
+			case found && app == opts.App:
+				// Kept as is too for app specific settings, e.g. prowlarr.backfill
+				prefixed[key] = val
+				nested[setting] = val
+			default:
+				prefixed[key] = val
+			}
+			continue
+		}
+
+		if !opts.Legacy {
+			continue
+		}
+		key := envKey(name, opts.Transform)
+		if !slices.Contains(opts.Keys, key) {
+			continue
+		}
+		legacy[key] = val
+		deprecated = append(deprecated, DeprecatedEnv{
+			Name:        name,
+			Replacement: ENV_PREFIX + name,
+		})
+	}
+
+	k := koanf.New(".")
+	for _, values := range []map[string]interface{}{legacy, prefixed, nested} {
+		if err := k.Load(confmap.Provider(values, "."), nil); err != nil {
+			return nil, nil, err
+		}
+	}
+	slices.SortFunc(deprecated, func(a, b DeprecatedEnv) int {
+		return strings.Compare(a.Name, b.Name)
+	})
+	return k, deprecated, nil
+}
+
+// Keys returns the settings of the struct v from its koanf tags, nested
+// structs being sections, e.g. prowlarr.backfill.
+func Keys(v interface{}) []string {
+	var ret []string
+	t := reflect.Indirect(reflect.ValueOf(v)).Type()
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		name := field.Tag.Get("koanf")
+		if name == "" || name == "-" {
+			continue
+		}
+		if field.Type.Kind() == reflect.Struct && field.Type.PkgPath() != "time" {
+			for _, key := range Keys(reflect.New(field.Type).Interface()) {
+				ret = append(ret, name+"."+key)
+			}
+			continue
+		}
+		ret = append(ret, name)
+	}
+	return ret
+}
+
+// envKey maps the name of an environment variable to the setting it holds,
+// e.g. PROWLARR__BACKFILL_SINCE_DATE to prowlarr.backfill-since-date.
+func envKey(name string, transform func(string) string) string {
+	s := strings.ToLower(name)
+	s = strings.Replace(s, "__", ".", -1)
+	s = strings.Replace(s, "_", "-", -1)
+	if transform != nil {
+		s = transform(s)
+	}
+	return s
+}
+
+// loggedDeprecatedEnv holds the names of the legacy environment variables
+// LogDeprecatedEnv warned about already.
+var loggedDeprecatedEnv sync.Map
+
+// LogDeprecatedEnv warns about each legacy environment variable which was
+// read. Each is only warned about once, rather than again on every reload.
+func LogDeprecatedEnv(deprecated []DeprecatedEnv) {
+	for _, d := range deprecated {
+		if _, logged := loggedDeprecatedEnv.LoadOrStore(d.Name, true); logged {
+			continue
+		}
+		zap.S().Warnw("Environment variable is deprecated, use the prefixed one instead",
+			"name", d.Name,
+			"replacement", d.Replacement)
+	}
+}
@@ -0,0 +1,84 @@
+package config
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+	"go.uber.org/zap/zaptest/observer"
+)
+
+func TestLoadEnv(t *testing.T) {
+	require := require.New(t)
+	t.Setenv("URL", "http://legacy:8989")
+	t.Setenv("PORT", "1234")
+	t.Setenv("HOME", "/root")
+	t.Setenv("EXPORTARR_PORT", "4321")
+	t.Setenv("EXPORTARR_LOG_LEVEL", "debug")
+	t.Setenv("EXPORTARR_SONARR__LOG_LEVEL", "warn")
+	t.Setenv("EXPORTARR_RADARR__LOG_LEVEL", "error")
+
+	k, deprecated, err := LoadEnv(EnvOptions{App: "sonarr", Keys: Keys(Config{}), Legacy: true})
+	require.NoError(err)
+	require.Equal("http://legacy:8989", k.String("url"))
+	require.Equal("4321", k.String("port"))
+	require.Equal("warn", k.String("log-level"))
+	require.False(k.Exists("home"))
+	require.Equal([]DeprecatedEnv{
+		{Name: "PORT", Replacement: "EXPORTARR_PORT"},
+		{Name: "URL", Replacement: "EXPORTARR_URL"},
+	}, deprecated)
+
+	k, deprecated, err = LoadEnv(EnvOptions{App: "radarr", Keys: Keys(Config{})})
+	require.NoError(err)
+	require.False(k.Exists("url"))
+	require.Equal("error", k.String("log-level"))
+	require.Empty(deprecated)
+}
+
+func TestLoadConfig_DisableLegacyEnv(t *testing.T) {
+	require := require.New(t)
+	t.Setenv("URL", "http://legacy:8989")
+	t.Setenv("EXPORTARR_SONARR__URL", "http://sonarr:8989")
+
+	config, err := LoadConfig("radarr", testFlagSet())
+	require.NoError(err)
+	require.Equal("http://legacy:8989", config.URL)
+	require.Equal([]DeprecatedEnv{{Name: "URL", Replacement: "EXPORTARR_URL"}}, config.DeprecatedEnv())
+
+	config, err = LoadConfig("sonarr", testFlagSet())
+	require.NoError(err)
+	require.Equal("http://sonarr:8989", config.URL)
+
+	t.Setenv("EXPORTARR_DISABLE_LEGACY_ENV", "true")
+	config, err = LoadConfig("radarr", testFlagSet())
+	require.NoError(err)
+	require.Empty(config.URL)
+	require.Empty(config.DeprecatedEnv())
+}
+
+func TestLogDeprecatedEnv(t *testing.T) {
+	require := require.New(t)
+	core, logs := observer.New(zap.WarnLevel)
+	defer zap.ReplaceGlobals(zap.New(core))()
+
+	deprecated := []DeprecatedEnv{{Name: "TEST_LOG_ONCE", Replacement: "EXPORTARR_TEST_LOG_ONCE"}}
+	LogDeprecatedEnv(deprecated)
+	LogDeprecatedEnv(deprecated)
+	require.Equal(1, logs.Len(), "Each variable should be warned about once, not on every reload")
+
+	LogDeprecatedEnv(append(deprecated, DeprecatedEnv{Name: "TEST_LOG_ONCE_2", Replacement: "EXPORTARR_TEST_LOG_ONCE_2"}))
+	require.Equal(2, logs.Len())
+}
+
+func TestKeys(t *testing.T) {
+	type section struct {
+		Enabled bool `koanf:"enabled"`
+	}
+	keys := Keys(struct {
+		URL     string  `koanf:"url"`
+		Ignored string  `koanf:"-"`
+		Section section `koanf:"section"`
+	}{})
+	require.Equal(t, []string{"url", "section.enabled"}, keys)
+}
@@ -0,0 +1,140 @@
+package config
+
+import (
+	"fmt"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/knadh/koanf/parsers/toml"
+	"github.com/knadh/koanf/parsers/yaml"
+	"github.com/knadh/koanf/providers/confmap"
+	"github.com/knadh/koanf/providers/file"
+	"github.com/knadh/koanf/v2"
+	flag "github.com/spf13/pflag"
+	"golang.org/x/exp/slices"
+)
+
+// Apps which can have a section in the config file.
+var Apps = []string{"radarr", "sonarr", "lidarr", "readarr", "prowlarr", "bazarr", "sabnzbd"}
+
+// File is a YAML or TOML config file. Its top-level keys are named after the
+// flags; the section named after an app holds settings which only apply to
+// that app and override the top-level ones, e.g.
+//
+//	log-level: debug
+//	sonarr:
+//	  url: http://sonarr:8989
+//	  api-key: ...
+//	prowlarr:
+//	  backfill: true
+type File struct {
+	Path   string
+	k      *koanf.Koanf
+	fields map[string]string // Setting to the field of the file it is read from
+}
+
+// LoadFile reads the config file at path, resolving the settings of app.
+// The format is picked from the extension: .yaml, .yml or .toml.
+func LoadFile(path string, app string) (*File, error) {
+	var parser koanf.Parser
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".yaml", ".yml":
+		parser = yaml.Parser()
+	case ".toml":
+		parser = toml.Parser()
+	default:
+		return nil, fmt.Errorf("Unsupported config file %s, must be .yaml, .yml or .toml", path)
+	}
+
+	raw := koanf.New(".")
+	if err := raw.Load(file.Provider(path), parser); err != nil {
+		return nil, fmt.Errorf("Couldn't read config file %s: %w", path, err)
+	}
+
+	ret := &File{
+		Path:   path,
+		k:      koanf.New("."),
+		fields: map[string]string{},
+	}
+	values := map[string]interface{}{}
+	for key, val := range raw.All() {
+		section := strings.SplitN(key, ".", 2)[0]
+		if slices.Contains(Apps, section) && section != app {
+			continue
+		}
+		values[key] = val
+		if section != app {
+			ret.fields[key] = key
+		}
+	}
+	// The app's section overrides the top-level settings, but is also kept
+	// as is for the app specific settings, e.g. prowlarr.backfill.
+	if app != "" {
+		for key, val := range raw.Cut(app).All() {
+			values[key] = val
+			ret.fields[key] = app + "." + key
+		}
+	}
+	if err := ret.k.Load(confmap.Provider(values, "."), nil); err != nil {
+		return nil, err
+	}
+	return ret, nil
+}
+
+// Koanf returns the settings of the file.
+func (f *File) Koanf() *koanf.Koanf {
+	return f.k
+}
+
+// Shadow records that the given settings are overridden by the environment.
+func (f *File) Shadow(keys ...string) {
+	if f == nil {
+		return
+	}
+	for _, key := range keys {
+		if parts := strings.SplitN(key, ".", 2); len(parts) == 2 && slices.Contains(Apps, parts[0]) {
+			key = parts[1]
+		}
+		for setting := range f.fields {
+			if setting == key || strings.HasPrefix(setting, key+".") {
+				delete(f.fields, setting)
+			}
+		}
+	}
+}
+
+// ShadowFlags records that the changed flags override the file.
+func (f *File) ShadowFlags(flags *flag.FlagSet) {
+	flags.Visit(func(fl *flag.Flag) {
+		f.Shadow(fl.Name)
+	})
+}
+
+// Explain adds the fields of the file which set the settings named in err,
+// so a bad value can be found in the file.
+func (f *File) Explain(err error) error {
+	if f == nil || err == nil {
+		return err
+	}
+	msg := err.Error()
+	var found []string
+	for setting, field := range f.fields {
+		parts := strings.Split(setting, ".")
+		for i := range parts {
+			name := strings.Join(parts[:i+1], ".")
+			if regexp.MustCompile(`(?i)(^|[^a-z0-9-])` + regexp.QuoteMeta(name) + `($|[^a-z0-9-])`).MatchString(msg) {
+				if !slices.Contains(found, field) {
+					found = append(found, field)
+				}
+				break
+			}
+		}
+	}
+	if len(found) == 0 {
+		return err
+	}
+	sort.Strings(found)
+	return fmt.Errorf("%w (set by %s in %s)", err, strings.Join(found, ", "), f.Path)
+}
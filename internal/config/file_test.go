@@ -0,0 +1,74 @@
+package config
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestLoadConfig_File(t *testing.T) {
+	for _, path := range []string{"test_fixtures/config.yaml", "test_fixtures/config.toml"} {
+		t.Run(path, func(t *testing.T) {
+			require := require.New(t)
+			flags := testFlagSet()
+			flags.Set("config-file", path)
+
+			config, err := LoadConfig("radarr", flags)
+			require.NoError(err)
+			require.Equal("radarr", config.App)
+			require.Equal("debug", config.LogLevel)
+			require.Equal(9707, config.Port)
+			require.Equal("http://radarr:7878", config.URL)
+			require.Equal("abcdef0123456789abcdef0123456789", config.ApiKey)
+			require.Equal(30*time.Second, config.RefreshIntervalFor("queue"))
+			require.NoError(config.Validate())
+
+			// The app's section overrides the top-level settings
+			config, err = LoadConfig("sonarr", flags)
+			require.NoError(err)
+			require.Equal("http://sonarr:8989", config.URL)
+			require.Equal(9708, config.Port)
+		})
+	}
+}
+
+func TestLoadConfig_FilePrecedence(t *testing.T) {
+	require := require.New(t)
+	t.Setenv("CONFIG_FILE", "test_fixtures/config.yaml")
+	t.Setenv("URL", "http://env:8989")
+	t.Setenv("PORT", "1234")
+
+	flags := testFlagSet()
+	flags.Set("port", "4321")
+
+	config, err := LoadConfig("sonarr", flags)
+	require.NoError(err)
+	require.Equal("test_fixtures/config.yaml", config.ConfigFile)
+	require.Equal("http://env:8989", config.URL)
+	require.Equal(4321, config.Port)
+	require.Equal("debug", config.LogLevel)
+}
+
+func TestLoadConfig_FileErrors(t *testing.T) {
+	require := require.New(t)
+	flags := testFlagSet()
+	flags.Set("config-file", "test_fixtures/config-invalid.yaml")
+
+	config, err := LoadConfig("sonarr", flags)
+	require.NoError(err)
+	err = config.Validate()
+	require.ErrorContains(err, "set by sonarr.log-level in test_fixtures/config-invalid.yaml")
+
+	// Settings overridden by the environment aren't blamed on the file
+	t.Setenv("LOG_LEVEL", "verbose")
+	config, err = LoadConfig("sonarr", flags)
+	require.NoError(err)
+	err = config.Validate()
+	require.Error(err)
+	require.NotContains(err.Error(), "set by")
+
+	flags.Set("config-file", "test_fixtures/api_key")
+	_, err = LoadConfig("sonarr", flags)
+	require.ErrorContains(err, "must be .yaml, .yml or .toml")
+}
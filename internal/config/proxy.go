@@ -0,0 +1,45 @@
+package config
+
+import (
+	"fmt"
+	"net/url"
+
+	"github.com/onedr0p/exportarr/internal/client"
+)
+
+// ParseProxyURL parses the proxy-url setting, nil when it's unset so the
+// proxy environment variables apply.
+func ParseProxyURL(proxyURL string) (*url.URL, error) {
+	if proxyURL == "" {
+		return nil, nil
+	}
+	u, err := url.Parse(proxyURL)
+	if err != nil {
+		return nil, fmt.Errorf("proxy-url: %w", err)
+	}
+	switch u.Scheme {
+	case "http", "https", "socks5", "socks5h":
+	default:
+		return nil, fmt.Errorf("proxy-url: unsupported scheme %s, must be one of: http, https, socks5, socks5h", u.Scheme)
+	}
+	if u.Host == "" {
+		return nil, fmt.Errorf("proxy-url: %s has no host", u.Redacted())
+	}
+	return u, nil
+}
+
+// ValidateUpstream checks the app's URL, which may be a unix:// socket, and
+// the proxy it's reached through.
+func ValidateUpstream(appURL string, proxyURL string) error {
+	_, socket, err := client.SocketURL(appURL)
+	if err != nil {
+		return fmt.Errorf("url: %w", err)
+	}
+	if _, err := ParseProxyURL(proxyURL); err != nil {
+		return err
+	}
+	if socket != "" && proxyURL != "" {
+		return fmt.Errorf("proxy-url can't be used with a unix:// url")
+	}
+	return nil
+}
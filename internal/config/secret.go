@@ -0,0 +1,194 @@
+package config
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"os"
+	"os/exec"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/knadh/koanf/v2"
+)
+
+// SECRET_TIMEOUT bounds how long a secret source may take to return a secret.
+var SECRET_TIMEOUT = 30 * time.Second
+
+// SecretSource resolves secret settings. Besides being set directly, a secret
+// can be read from a file named by the setting's -file variant, e.g.
+// auth-password-file, or its value can name a source to get it from:
+//
+//	exec:<command> [args...]  the output of the command, run without a shell
+//	vault:<path>#<field>      a field of a Vault KV secret, e.g. vault:secret/data/sonarr#api-key
+//
+// Errors never include the secret itself.
+type SecretSource struct {
+	VaultAddr  string
+	VaultToken string
+	cache      *secretCache // secrets already resolved, see Cached
+}
+
+// secretCache holds the secrets resolved by the exec: and vault: sources,
+// keyed by the value naming them.
+type secretCache struct {
+	mutex  sync.Mutex
+	values map[string]string
+}
+
+// Cached returns a copy of s which resolves each exec: and vault: secret only
+// once, e.g. for as long as a loaded config is used. Failures aren't cached,
+// so they're tried again.
+func (s SecretSource) Cached() SecretSource {
+	s.cache = &secretCache{values: map[string]string{}}
+	return s
+}
+
+// Resolve sets the secret key of k from its -file variant when set, and then
+// from the source its value names, if any.
+func (s SecretSource) Resolve(k *koanf.Koanf, key string) error {
+	val := k.String(key)
+	if file := k.String(key + "-file"); file != "" {
+		data, err := os.ReadFile(file)
+		if err != nil {
+			return fmt.Errorf("Couldn't read %s-file %w", key, err)
+		}
+		val = strings.TrimSpace(string(data))
+	}
+	val, err := s.Value(val)
+	if err != nil {
+		return fmt.Errorf("%s: %w", key, err)
+	}
+	if val == "" && !k.Exists(key) {
+		return nil
+	}
+	if err := k.Set(key, val); err != nil {
+		return fmt.Errorf("Couldn't merge %s into config: %w", key, err)
+	}
+	return nil
+}
+
+// ResolveValues replaces each value of the setting key, a map like headers,
+// with the secret it names.
+func (s SecretSource) ResolveValues(key string, m map[string]string) error {
+	for name, val := range m {
+		val, err := s.Value(val)
+		if err != nil {
+			return fmt.Errorf("%s: %s: %w", key, name, err)
+		}
+		m[name] = val
+	}
+	return nil
+}
+
+// Value returns the secret named by val, or val itself when it doesn't name a source.
+func (s SecretSource) Value(val string) (string, error) {
+	var resolve func(string) (string, error)
+	switch {
+	case strings.HasPrefix(val, "exec:"):
+		resolve = func(val string) (string, error) { return s.exec(strings.TrimPrefix(val, "exec:")) }
+	case strings.HasPrefix(val, "vault:"):
+		resolve = func(val string) (string, error) { return s.vault(strings.TrimPrefix(val, "vault:")) }
+	default:
+		return val, nil
+	}
+	if s.cache == nil {
+		return resolve(val)
+	}
+
+	s.cache.mutex.Lock()
+	secret, ok := s.cache.values[val]
+	s.cache.mutex.Unlock()
+	if ok {
+		return secret, nil
+	}
+	// The source isn't called with the lock held, so a slow one doesn't hold up other secrets
+	secret, err := resolve(val)
+	if err != nil {
+		return "", err
+	}
+	s.cache.mutex.Lock()
+	s.cache.values[val] = secret
+	s.cache.mutex.Unlock()
+	return secret, nil
+}
+
+func (s SecretSource) exec(command string) (string, error) {
+	args := strings.Fields(command)
+	if len(args) == 0 {
+		return "", fmt.Errorf("exec: secret source needs a command")
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), SECRET_TIMEOUT)
+	defer cancel()
+	// Only stdout is captured, stderr is passed through for troubleshooting.
+	cmd := exec.CommandContext(ctx, args[0], args[1:]...)
+	cmd.Stderr = os.Stderr
+	out, err := cmd.Output()
+	if err != nil {
+		return "", fmt.Errorf("Couldn't run secret command %s: %w", args[0], err)
+	}
+	return strings.TrimSpace(string(out)), nil
+}
+
+// vault reads a field of a secret from Vault's HTTP API. Both KV version 1
+// and 2 secrets are supported, the latter nesting the fields in data.data.
+func (s SecretSource) vault(ref string) (string, error) {
+	path, field, ok := strings.Cut(ref, "#")
+	if !ok || path == "" || field == "" {
+		return "", fmt.Errorf("vault: secret must be formatted as vault:<path>#<field>")
+	}
+	addr := s.VaultAddr
+	if addr == "" {
+		addr = os.Getenv("VAULT_ADDR")
+	}
+	token := s.VaultToken
+	if token == "" {
+		token = os.Getenv("VAULT_TOKEN")
+	}
+	if addr == "" {
+		return "", fmt.Errorf("vault-addr is required for vault: secrets")
+	}
+	u, err := url.JoinPath(addr, "v1", path)
+	if err != nil {
+		return "", fmt.Errorf("Invalid vault-addr: %w", err)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), SECRET_TIMEOUT)
+	defer cancel()
+	req, err := http.NewRequestWithContext(ctx, "GET", u, nil)
+	if err != nil {
+		return "", err
+	}
+	if token != "" {
+		req.Header.Set("X-Vault-Token", token)
+	}
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return "", fmt.Errorf("Couldn't read vault secret %s: %w", path, err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return "", fmt.Errorf("Couldn't read vault secret %s: Received Status Code %d", path, resp.StatusCode)
+	}
+
+	var body struct {
+		Data map[string]interface{} `json:"data"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&body); err != nil {
+		return "", fmt.Errorf("Couldn't parse vault secret %s: %w", path, err)
+	}
+	fields := body.Data
+	if nested, ok := fields["data"].(map[string]interface{}); ok {
+		if _, isMetadata := fields["metadata"]; isMetadata {
+			fields = nested
+		}
+	}
+	val, ok := fields[field].(string)
+	if !ok {
+		return "", fmt.Errorf("vault secret %s has no string field %s", path, field)
+	}
+	return val, nil
+}
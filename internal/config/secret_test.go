@@ -0,0 +1,162 @@
+package config
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+
+	"github.com/knadh/koanf/v2"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSecretSource_Resolve(t *testing.T) {
+	require := require.New(t)
+	k := koanf.New(".")
+
+	require.NoError(SecretSource{}.Resolve(k, "api-key"))
+	require.False(k.Exists("api-key"), "Unset secrets should stay unset")
+
+	k.Set("api-key", "plain")
+	require.NoError(SecretSource{}.Resolve(k, "api-key"))
+	require.Equal("plain", k.String("api-key"))
+
+	k.Set("api-key-file", "test_fixtures/api_key")
+	require.NoError(SecretSource{}.Resolve(k, "api-key"))
+	require.Equal("abcdef0123456789abcdef0123456783", k.String("api-key"), "The file should override the value")
+
+	k.Set("api-key-file", "test_fixtures/missing")
+	require.Error(SecretSource{}.Resolve(k, "api-key"))
+}
+
+func TestSecretSource_Exec(t *testing.T) {
+	require := require.New(t)
+
+	val, err := SecretSource{}.Value("exec:echo  top-secret ")
+	require.NoError(err)
+	require.Equal("top-secret", val)
+
+	_, err = SecretSource{}.Value("exec:false top-secret")
+	require.Error(err)
+	require.NotContains(err.Error(), "top-secret", "Errors should not include the command's arguments")
+
+	_, err = SecretSource{}.Value("exec:")
+	require.Error(err)
+}
+
+func TestSecretSource_Cached(t *testing.T) {
+	require := require.New(t)
+	var requests atomic.Int32
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests.Add(1)
+		if r.URL.Path != "/v1/kv/sonarr" {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		w.Write([]byte(`{"data": {"api-key": "kv1-secret"}}`))
+	}))
+	defer ts.Close()
+
+	source := SecretSource{VaultAddr: ts.URL}
+	for i := 0; i < 2; i++ {
+		val, err := source.Value("vault:kv/sonarr#api-key")
+		require.NoError(err)
+		require.Equal("kv1-secret", val)
+	}
+	require.Equal(int32(2), requests.Load(), "Secrets shouldn't be cached by default")
+
+	cached := source.Cached()
+	for i := 0; i < 2; i++ {
+		val, err := cached.Value("vault:kv/sonarr#api-key")
+		require.NoError(err)
+		require.Equal("kv1-secret", val)
+	}
+	require.Equal(int32(3), requests.Load(), "Cached secrets should be resolved once")
+
+	for i := 0; i < 2; i++ {
+		_, err := cached.Value("vault:kv/radarr#api-key")
+		require.Error(err)
+	}
+	require.Equal(int32(5), requests.Load(), "Failures shouldn't be cached")
+}
+
+func TestSecretSource_Vault(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("X-Vault-Token") != "token" {
+			w.WriteHeader(http.StatusForbidden)
+			return
+		}
+		switch r.URL.Path {
+		case "/v1/secret/data/sonarr":
+			w.Write([]byte(`{"data": {"data": {"api-key": "kv2-secret"}, "metadata": {"version": 1}}}`))
+		case "/v1/kv/sonarr":
+			w.Write([]byte(`{"data": {"api-key": "kv1-secret"}}`))
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	defer ts.Close()
+
+	parameters := []struct {
+		name        string
+		source      SecretSource
+		ref         string
+		expected    string
+		shouldError bool
+	}{
+		{
+			name:     "kv2",
+			source:   SecretSource{VaultAddr: ts.URL, VaultToken: "token"},
+			ref:      "vault:secret/data/sonarr#api-key",
+			expected: "kv2-secret",
+		},
+		{
+			name:     "kv1",
+			source:   SecretSource{VaultAddr: ts.URL, VaultToken: "token"},
+			ref:      "vault:kv/sonarr#api-key",
+			expected: "kv1-secret",
+		},
+		{
+			name:        "missing-field",
+			source:      SecretSource{VaultAddr: ts.URL, VaultToken: "token"},
+			ref:         "vault:kv/sonarr#password",
+			shouldError: true,
+		},
+		{
+			name:        "bad-token",
+			source:      SecretSource{VaultAddr: ts.URL, VaultToken: "wrong"},
+			ref:         "vault:kv/sonarr#api-key",
+			shouldError: true,
+		},
+		{
+			name:        "missing-addr",
+			ref:         "vault:kv/sonarr#api-key",
+			shouldError: true,
+		},
+		{
+			name:        "bad-format",
+			source:      SecretSource{VaultAddr: ts.URL, VaultToken: "token"},
+			ref:         "vault:kv/sonarr",
+			shouldError: true,
+		},
+	}
+
+	for _, p := range parameters {
+		t.Run(p.name, func(t *testing.T) {
+			require := require.New(t)
+			t.Setenv("VAULT_ADDR", "")
+			t.Setenv("VAULT_TOKEN", "")
+
+			val, err := p.source.Value(p.ref)
+			if p.shouldError {
+				require.Error(err)
+				if p.source.VaultToken != "" {
+					require.NotContains(err.Error(), p.source.VaultToken)
+				}
+				return
+			}
+			require.NoError(err)
+			require.Equal(p.expected, val)
+		})
+	}
+}
@@ -0,0 +1,133 @@
+package config
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/hex"
+	"fmt"
+	"os"
+	"strings"
+)
+
+// TLSConfig configures how the certificate of an app is verified and which
+// client certificate is presented to it.
+type TLSConfig struct {
+	InsecureSkipVerify bool
+	CAFile             string   // PEM bundle of the CAs trusted besides the system ones
+	CertFile           string   // PEM client certificate for mTLS
+	KeyFile            string   // PEM key of the client certificate
+	ServerName         string   // Name verified in the app's certificate, instead of the URL's host
+	PinnedSHA256       []string // SHA-256 fingerprints, one of which the app's certificate, or with verification its chain, must match
+}
+
+// Files returns the files the TLS settings are read from.
+func (c TLSConfig) Files() []string {
+	var ret []string
+	for _, file := range []string{c.CAFile, c.CertFile, c.KeyFile} {
+		if file != "" {
+			ret = append(ret, file)
+		}
+	}
+	return ret
+}
+
+// Validate checks that the files can be loaded and the fingerprints parsed.
+func (c TLSConfig) Validate() error {
+	_, err := c.Build()
+	return err
+}
+
+// Build returns the tls.Config of the settings, nil when they are all unset.
+// The client certificate is read again on every handshake, so a rotated
+// certificate is used by new connections without rebuilding the client.
+func (c TLSConfig) Build() (*tls.Config, error) {
+	if !c.InsecureSkipVerify && c.CAFile == "" && c.CertFile == "" && c.KeyFile == "" &&
+		c.ServerName == "" && len(c.PinnedSHA256) == 0 {
+		return nil, nil
+	}
+	ret := &tls.Config{
+		InsecureSkipVerify: c.InsecureSkipVerify, //nolint:gosec
+		ServerName:         c.ServerName,
+	}
+
+	if c.CAFile != "" {
+		pem, err := os.ReadFile(c.CAFile)
+		if err != nil {
+			return nil, fmt.Errorf("Couldn't read ca-file %w", err)
+		}
+		pool, err := x509.SystemCertPool()
+		if err != nil {
+			pool = x509.NewCertPool()
+		}
+		if !pool.AppendCertsFromPEM(pem) {
+			return nil, fmt.Errorf("ca-file %s contains no PEM certificates", c.CAFile)
+		}
+		ret.RootCAs = pool
+	}
+
+	if (c.CertFile == "") != (c.KeyFile == "") {
+		return nil, fmt.Errorf("client-cert and client-key must be set together")
+	}
+	if c.CertFile != "" {
+		if _, err := tls.LoadX509KeyPair(c.CertFile, c.KeyFile); err != nil {
+			return nil, fmt.Errorf("Couldn't load client-cert and client-key: %w", err)
+		}
+		ret.GetClientCertificate = func(*tls.CertificateRequestInfo) (*tls.Certificate, error) {
+			cert, err := tls.LoadX509KeyPair(c.CertFile, c.KeyFile)
+			if err != nil {
+				return nil, fmt.Errorf("Couldn't load client-cert and client-key: %w", err)
+			}
+			return &cert, nil
+		}
+	}
+
+	if len(c.PinnedSHA256) > 0 {
+		pins := make([][]byte, 0, len(c.PinnedSHA256))
+		for _, pin := range c.PinnedSHA256 {
+			fingerprint, err := parseFingerprint(pin)
+			if err != nil {
+				return nil, err
+			}
+			pins = append(pins, fingerprint)
+		}
+		// VerifyConnection also runs with InsecureSkipVerify, so pinning
+		// can replace verification for self-signed certificates. Only
+		// certificates the server proved to hold are matched: without
+		// verification that's the leaf alone, since the server can send
+		// any certificate after it, and otherwise the verified chains.
+		ret.VerifyConnection = func(state tls.ConnectionState) error {
+			var certs []*x509.Certificate
+			if c.InsecureSkipVerify {
+				if len(state.PeerCertificates) > 0 {
+					certs = state.PeerCertificates[:1]
+				}
+			} else {
+				for _, chain := range state.VerifiedChains {
+					certs = append(certs, chain...)
+				}
+			}
+			for _, cert := range certs {
+				sum := sha256.Sum256(cert.Raw)
+				for _, pin := range pins {
+					if bytes.Equal(sum[:], pin) {
+						return nil
+					}
+				}
+			}
+			return fmt.Errorf("certificate of %s doesn't match any of tls-pin-sha256", state.ServerName)
+		}
+	}
+	return ret, nil
+}
+
+// parseFingerprint parses a hex SHA-256 fingerprint, optionally separated by
+// colons like the output of `openssl x509 -fingerprint -sha256`.
+func parseFingerprint(s string) ([]byte, error) {
+	ret, err := hex.DecodeString(strings.ReplaceAll(s, ":", ""))
+	if err != nil || len(ret) != sha256.Size {
+		return nil, fmt.Errorf("tls-pin-sha256: %s is not a hex SHA-256 fingerprint", s)
+	}
+	return ret, nil
+}
@@ -0,0 +1,220 @@
+package config
+
+import (
+	"crypto/sha256"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/hex"
+	"encoding/pem"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestTLSConfig_Build(t *testing.T) {
+	serverCert, serverKey := writeCert(t, t.TempDir())
+	clientCert, clientKey := writeCert(t, t.TempDir())
+
+	// The server's certificate is only valid for localhost and requires a client certificate.
+	pair, err := tls.LoadX509KeyPair(serverCert, serverKey)
+	require.NoError(t, err)
+	clientPEM, err := os.ReadFile(clientCert)
+	require.NoError(t, err)
+	clientCAs := x509.NewCertPool()
+	clientCAs.AppendCertsFromPEM(clientPEM)
+	ts := httptest.NewUnstartedServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	ts.TLS = &tls.Config{
+		Certificates: []tls.Certificate{pair},
+		ClientAuth:   tls.RequireAndVerifyClientCert,
+		ClientCAs:    clientCAs,
+	}
+	ts.StartTLS()
+	defer ts.Close()
+
+	serverPEM, err := os.ReadFile(serverCert)
+	require.NoError(t, err)
+	block, _ := pem.Decode(serverPEM)
+	sum := sha256.Sum256(block.Bytes)
+	pin := hex.EncodeToString(sum[:])
+
+	parameters := []struct {
+		name        string
+		config      TLSConfig
+		shouldError bool
+	}{
+		{
+			name: "ca-and-client-cert",
+			config: TLSConfig{
+				CAFile:     serverCert,
+				CertFile:   clientCert,
+				KeyFile:    clientKey,
+				ServerName: "localhost",
+			},
+		},
+		{
+			name: "pinned",
+			config: TLSConfig{
+				InsecureSkipVerify: true,
+				CertFile:           clientCert,
+				KeyFile:            clientKey,
+				PinnedSHA256:       []string{"00" + pin[2:], pin},
+			},
+		},
+		{
+			name: "pinned-verified",
+			config: TLSConfig{
+				CAFile:       serverCert,
+				CertFile:     clientCert,
+				KeyFile:      clientKey,
+				ServerName:   "localhost",
+				PinnedSHA256: []string{pin},
+			},
+		},
+		{
+			name:        "unknown-ca",
+			config:      TLSConfig{CertFile: clientCert, KeyFile: clientKey, ServerName: "localhost"},
+			shouldError: true,
+		},
+		{
+			name:        "wrong-server-name",
+			config:      TLSConfig{CAFile: serverCert, CertFile: clientCert, KeyFile: clientKey},
+			shouldError: true,
+		},
+		{
+			name:        "missing-client-cert",
+			config:      TLSConfig{CAFile: serverCert, ServerName: "localhost"},
+			shouldError: true,
+		},
+		{
+			name: "pin-mismatch",
+			config: TLSConfig{
+				InsecureSkipVerify: true,
+				CertFile:           clientCert,
+				KeyFile:            clientKey,
+				PinnedSHA256:       []string{"00" + pin[2:]},
+			},
+			shouldError: true,
+		},
+	}
+
+	for _, p := range parameters {
+		t.Run(p.name, func(t *testing.T) {
+			require := require.New(t)
+			tlsConfig, err := p.config.Build()
+			require.NoError(err)
+			client := &http.Client{Transport: &http.Transport{TLSClientConfig: tlsConfig}}
+			resp, err := client.Get(ts.URL)
+			if p.shouldError {
+				require.Error(err)
+				return
+			}
+			require.NoError(err)
+			resp.Body.Close()
+		})
+	}
+}
+
+func TestTLSConfig_Validate(t *testing.T) {
+	cert, key := writeCert(t, t.TempDir())
+
+	parameters := []struct {
+		name        string
+		config      TLSConfig
+		shouldError bool
+	}{
+		{
+			name:   "empty",
+			config: TLSConfig{},
+		},
+		{
+			name: "good",
+			config: TLSConfig{
+				CAFile:       cert,
+				CertFile:     cert,
+				KeyFile:      key,
+				PinnedSHA256: []string{"AB:CD:EF:01:23:45:67:89:AB:CD:EF:01:23:45:67:89:AB:CD:EF:01:23:45:67:89:AB:CD:EF:01:23:45:67:89"},
+			},
+		},
+		{
+			name:        "missing-ca-file",
+			config:      TLSConfig{CAFile: "missing.pem"},
+			shouldError: true,
+		},
+		{
+			name:        "ca-file-without-certificates",
+			config:      TLSConfig{CAFile: key},
+			shouldError: true,
+		},
+		{
+			name:        "client-cert-without-key",
+			config:      TLSConfig{CertFile: cert},
+			shouldError: true,
+		},
+		{
+			name:        "bad-pin",
+			config:      TLSConfig{PinnedSHA256: []string{"abcdef"}},
+			shouldError: true,
+		},
+	}
+
+	for _, p := range parameters {
+		t.Run(p.name, func(t *testing.T) {
+			require := require.New(t)
+			err := p.config.Validate()
+			if p.shouldError {
+				require.Error(err)
+			} else {
+				require.NoError(err)
+			}
+		})
+	}
+}
+
+func TestTLSConfig_Build_PinAfterLeaf(t *testing.T) {
+	pinnedCert, _ := writeCert(t, t.TempDir())
+	otherCert, otherKey := writeCert(t, t.TempDir())
+
+	// The server holds the key of an unrelated certificate and appends the
+	// pinned certificate, which is public, to its chain.
+	pair, err := tls.LoadX509KeyPair(otherCert, otherKey)
+	require.NoError(t, err)
+	pinnedPEM, err := os.ReadFile(pinnedCert)
+	require.NoError(t, err)
+	block, _ := pem.Decode(pinnedPEM)
+	pair.Certificate = append(pair.Certificate, block.Bytes)
+	sum := sha256.Sum256(block.Bytes)
+	pin := hex.EncodeToString(sum[:])
+
+	ts := httptest.NewUnstartedServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	ts.TLS = &tls.Config{Certificates: []tls.Certificate{pair}}
+	ts.StartTLS()
+	defer ts.Close()
+
+	parameters := []struct {
+		name   string
+		config TLSConfig
+	}{
+		{
+			name:   "insecure-skip-verify",
+			config: TLSConfig{InsecureSkipVerify: true, PinnedSHA256: []string{pin}},
+		},
+		{
+			name:   "verified",
+			config: TLSConfig{CAFile: otherCert, ServerName: "localhost", PinnedSHA256: []string{pin}},
+		},
+	}
+
+	for _, p := range parameters {
+		t.Run(p.name, func(t *testing.T) {
+			require := require.New(t)
+			tlsConfig, err := p.config.Build()
+			require.NoError(err)
+			client := &http.Client{Transport: &http.Transport{TLSClientConfig: tlsConfig}}
+			_, err = client.Get(ts.URL)
+			require.ErrorContains(err, "tls-pin-sha256")
+		})
+	}
+}
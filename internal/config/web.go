@@ -0,0 +1,129 @@
+package config
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"fmt"
+	"os"
+
+	"golang.org/x/crypto/bcrypt"
+	"gopkg.in/yaml.v3"
+)
+
+// WebConfig secures the exporter's own HTTP server. The file format is the one
+// used by Prometheus' exporter-toolkit, so existing web config files can be reused.
+type WebConfig struct {
+	TLSServerConfig TLSServerConfig   `yaml:"tls_server_config"`
+	BasicAuthUsers  map[string]string `yaml:"basic_auth_users"` // Username to bcrypt hash of the password
+}
+
+type TLSServerConfig struct {
+	CertFile       string `yaml:"cert_file"`
+	KeyFile        string `yaml:"key_file"`
+	ClientCAFile   string `yaml:"client_ca_file"`
+	ClientAuthType string `yaml:"client_auth_type"`
+	MinVersion     string `yaml:"min_version"`
+}
+
+var clientAuthTypes = map[string]tls.ClientAuthType{
+	"NoClientCert":               tls.NoClientCert,
+	"RequestClientCert":          tls.RequestClientCert,
+	"RequireAnyClientCert":       tls.RequireAnyClientCert,
+	"VerifyClientCertIfGiven":    tls.VerifyClientCertIfGiven,
+	"RequireAndVerifyClientCert": tls.RequireAndVerifyClientCert,
+}
+
+var tlsVersions = map[string]uint16{
+	"TLS10": tls.VersionTLS10,
+	"TLS11": tls.VersionTLS11,
+	"TLS12": tls.VersionTLS12,
+	"TLS13": tls.VersionTLS13,
+}
+
+// LoadWebConfig reads and validates the web config file at path.
+func LoadWebConfig(path string) (*WebConfig, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("Couldn't read web config file %w", err)
+	}
+	var ret WebConfig
+	if err := yaml.Unmarshal(data, &ret); err != nil {
+		return nil, fmt.Errorf("Couldn't parse web config file %s: %w", path, err)
+	}
+	if err := ret.Validate(); err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	return &ret, nil
+}
+
+func (c *WebConfig) Validate() error {
+	t := c.TLSServerConfig
+	if (t.CertFile == "") != (t.KeyFile == "") {
+		return fmt.Errorf("tls_server_config: cert_file and key_file must be set together")
+	}
+	if !c.TLSEnabled() && (t.ClientCAFile != "" || t.ClientAuthType != "") {
+		return fmt.Errorf("tls_server_config: client_ca_file and client_auth_type require cert_file and key_file")
+	}
+	if _, ok := clientAuthTypes[t.ClientAuthType]; t.ClientAuthType != "" && !ok {
+		return fmt.Errorf("tls_server_config: unknown client_auth_type %s", t.ClientAuthType)
+	}
+	if _, ok := tlsVersions[t.MinVersion]; t.MinVersion != "" && !ok {
+		return fmt.Errorf("tls_server_config: unknown min_version %s", t.MinVersion)
+	}
+	if t.ClientCAFile == "" && (t.ClientAuthType == "VerifyClientCertIfGiven" || t.ClientAuthType == "RequireAndVerifyClientCert") {
+		return fmt.Errorf("tls_server_config: client_auth_type %s requires client_ca_file", t.ClientAuthType)
+	}
+	for user, hash := range c.BasicAuthUsers {
+		if _, err := bcrypt.Cost([]byte(hash)); err != nil {
+			return fmt.Errorf("basic_auth_users: password of %s must be a bcrypt hash: %w", user, err)
+		}
+	}
+	return nil
+}
+
+// TLSEnabled reports whether the server should be served over TLS.
+func (c *WebConfig) TLSEnabled() bool {
+	return c.TLSServerConfig.CertFile != ""
+}
+
+// TLSConfig builds the server's TLS config. The certificate is read again on
+// every handshake, so renewed certificates are picked up without a restart.
+func (c *WebConfig) TLSConfig() (*tls.Config, error) {
+	t := c.TLSServerConfig
+	// Fail at startup rather than on the first handshake.
+	if _, err := tls.LoadX509KeyPair(t.CertFile, t.KeyFile); err != nil {
+		return nil, fmt.Errorf("Couldn't load TLS certificate: %w", err)
+	}
+
+	ret := &tls.Config{
+		MinVersion: tls.VersionTLS12,
+		GetCertificate: func(*tls.ClientHelloInfo) (*tls.Certificate, error) {
+			cert, err := tls.LoadX509KeyPair(t.CertFile, t.KeyFile)
+			if err != nil {
+				return nil, fmt.Errorf("Couldn't load TLS certificate: %w", err)
+			}
+			return &cert, nil
+		},
+	}
+	if t.MinVersion != "" {
+		ret.MinVersion = tlsVersions[t.MinVersion]
+	}
+
+	if t.ClientCAFile != "" {
+		data, err := os.ReadFile(t.ClientCAFile)
+		if err != nil {
+			return nil, fmt.Errorf("Couldn't read client CA file %w", err)
+		}
+		pool := x509.NewCertPool()
+		if !pool.AppendCertsFromPEM(data) {
+			return nil, fmt.Errorf("No certificates found in client CA file %s", t.ClientCAFile)
+		}
+		ret.ClientCAs = pool
+		// A client CA without an explicit policy means clients must present a valid certificate.
+		ret.ClientAuth = tls.RequireAndVerifyClientCert
+	}
+	if t.ClientAuthType != "" {
+		ret.ClientAuth = clientAuthTypes[t.ClientAuthType]
+	}
+	return ret, nil
+}
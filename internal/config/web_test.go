@@ -0,0 +1,129 @@
+package config
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"math/big"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+// writeCert writes a self-signed certificate and its key to dir.
+func writeCert(t *testing.T, dir string) (string, string) {
+	require := require.New(t)
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(err)
+	template := &x509.Certificate{
+		SerialNumber: big.NewInt(1),
+		Subject:      pkix.Name{CommonName: "localhost"},
+		NotBefore:    time.Now(),
+		NotAfter:     time.Now().Add(time.Hour),
+		DNSNames:     []string{"localhost"},
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	require.NoError(err)
+	keyDer, err := x509.MarshalECPrivateKey(key)
+	require.NoError(err)
+
+	certFile := filepath.Join(dir, "cert.pem")
+	keyFile := filepath.Join(dir, "key.pem")
+	require.NoError(os.WriteFile(certFile, pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der}), 0600))
+	require.NoError(os.WriteFile(keyFile, pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: keyDer}), 0600))
+	return certFile, keyFile
+}
+
+func TestLoadWebConfig(t *testing.T) {
+	require := require.New(t)
+	c, err := LoadWebConfig("test_fixtures/web-config.yaml")
+	require.NoError(err)
+	require.False(c.TLSEnabled())
+	require.Contains(c.BasicAuthUsers, "prometheus")
+
+	_, err = LoadWebConfig("test_fixtures/missing.yaml")
+	require.Error(err)
+}
+
+func TestWebConfig_TLSConfig(t *testing.T) {
+	require := require.New(t)
+	dir := t.TempDir()
+	certFile, keyFile := writeCert(t, dir)
+
+	c := &WebConfig{TLSServerConfig: TLSServerConfig{
+		CertFile:     certFile,
+		KeyFile:      keyFile,
+		ClientCAFile: certFile,
+		MinVersion:   "TLS13",
+	}}
+	require.NoError(c.Validate())
+	require.True(c.TLSEnabled())
+	tlsConfig, err := c.TLSConfig()
+	require.NoError(err)
+	require.Equal(uint16(tls.VersionTLS13), tlsConfig.MinVersion)
+	require.Equal(tls.RequireAndVerifyClientCert, tlsConfig.ClientAuth, "A client CA should require client certificates by default")
+	cert, err := tlsConfig.GetCertificate(nil)
+	require.NoError(err)
+	require.NotNil(cert)
+
+	c.TLSServerConfig.ClientAuthType = "VerifyClientCertIfGiven"
+	tlsConfig, err = c.TLSConfig()
+	require.NoError(err)
+	require.Equal(tls.VerifyClientCertIfGiven, tlsConfig.ClientAuth)
+}
+
+func TestWebConfig_Validate(t *testing.T) {
+	parameters := []struct {
+		name   string
+		config WebConfig
+	}{
+		{
+			name:   "missing-key-file",
+			config: WebConfig{TLSServerConfig: TLSServerConfig{CertFile: "cert.pem"}},
+		},
+		{
+			name:   "client-ca-without-tls",
+			config: WebConfig{TLSServerConfig: TLSServerConfig{ClientCAFile: "ca.pem"}},
+		},
+		{
+			name: "unknown-client-auth-type",
+			config: WebConfig{TLSServerConfig: TLSServerConfig{
+				CertFile:       "cert.pem",
+				KeyFile:        "key.pem",
+				ClientAuthType: "Maybe",
+			}},
+		},
+		{
+			name: "verify-without-client-ca",
+			config: WebConfig{TLSServerConfig: TLSServerConfig{
+				CertFile:       "cert.pem",
+				KeyFile:        "key.pem",
+				ClientAuthType: "RequireAndVerifyClientCert",
+			}},
+		},
+		{
+			name: "unknown-min-version",
+			config: WebConfig{TLSServerConfig: TLSServerConfig{
+				CertFile:   "cert.pem",
+				KeyFile:    "key.pem",
+				MinVersion: "SSL3",
+			}},
+		},
+		{
+			name:   "plaintext-password",
+			config: WebConfig{BasicAuthUsers: map[string]string{"prometheus": "password"}},
+		},
+	}
+	for _, p := range parameters {
+		t.Run(p.name, func(t *testing.T) {
+			require.Error(t, p.config.Validate())
+		})
+	}
+}
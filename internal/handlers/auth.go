@@ -0,0 +1,65 @@
+package handlers
+
+import (
+	"crypto/sha256"
+	"net/http"
+	"sync"
+
+	"go.uber.org/zap"
+	"golang.org/x/crypto/bcrypt"
+	"golang.org/x/exp/slices"
+)
+
+// BasicAuthHandler requires one of users, mapped to the bcrypt hash of their
+// password, on every request except those for the unauthenticated paths.
+func BasicAuthHandler(users map[string]string, unauthenticated []string, next http.Handler) http.Handler {
+	// Compared against when the username is unknown, so unknown users take as
+	// long to reject as wrong passwords.
+	dummyHash, _ := bcrypt.GenerateFromPassword([]byte("exportarr"), bcrypt.DefaultCost)
+
+	// bcrypt is deliberately slow, so credentials which were already accepted are cached.
+	var (
+		verified = map[[32]byte]bool{}
+		mutex    sync.Mutex
+	)
+	check := func(user string, password string) bool {
+		hash, ok := users[user]
+		if !ok {
+			bcrypt.CompareHashAndPassword(dummyHash, []byte(password)) //nolint:errcheck
+			return false
+		}
+		key := sha256.Sum256([]byte(user + "\x00" + password + "\x00" + hash))
+
+		mutex.Lock()
+		found := verified[key]
+		mutex.Unlock()
+		if found {
+			return true
+		}
+		if bcrypt.CompareHashAndPassword([]byte(hash), []byte(password)) != nil {
+			return false
+		}
+		mutex.Lock()
+		verified[key] = true
+		mutex.Unlock()
+		return true
+	}
+
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if slices.Contains(unauthenticated, r.URL.Path) {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		user, password, ok := r.BasicAuth()
+		if !ok || !check(user, password) {
+			zap.S().Debugw("Unauthorized request",
+				"remote_addr", r.RemoteAddr,
+				"url", r.URL)
+			w.Header().Set("WWW-Authenticate", `Basic realm="exportarr"`)
+			http.Error(w, http.StatusText(http.StatusUnauthorized), http.StatusUnauthorized)
+			return
+		}
+		next.ServeHTTP(w, r)
+	})
+}
@@ -0,0 +1,49 @@
+package handlers
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestBasicAuthHandler(t *testing.T) {
+	users := map[string]string{
+		// password
+		"prometheus": "$2a$04$h8CRiaJSywWE7nOOTjbeuOv3miIUt7d.v9s7ALEk351R4pvAjsT5O",
+	}
+	handler := BasicAuthHandler(users, []string{"/healthz"}, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	}))
+
+	parameters := []struct {
+		name     string
+		path     string
+		user     string
+		password string
+		code     int
+	}{
+		{name: "valid", path: "/metrics", user: "prometheus", password: "password", code: http.StatusOK},
+		{name: "valid-cached", path: "/metrics", user: "prometheus", password: "password", code: http.StatusOK},
+		{name: "wrong-password", path: "/metrics", user: "prometheus", password: "wrong", code: http.StatusUnauthorized},
+		{name: "unknown-user", path: "/metrics", user: "grafana", password: "password", code: http.StatusUnauthorized},
+		{name: "no-credentials", path: "/metrics", code: http.StatusUnauthorized},
+		{name: "unauthenticated-path", path: "/healthz", code: http.StatusOK},
+	}
+	for _, p := range parameters {
+		t.Run(p.name, func(t *testing.T) {
+			require := require.New(t)
+			req := httptest.NewRequest("GET", p.path, nil)
+			if p.user != "" {
+				req.SetBasicAuth(p.user, p.password)
+			}
+			rec := httptest.NewRecorder()
+			handler.ServeHTTP(rec, req)
+			require.Equal(p.code, rec.Code)
+			if p.code == http.StatusUnauthorized {
+				require.NotEmpty(rec.Header().Get("WWW-Authenticate"))
+			}
+		})
+	}
+}
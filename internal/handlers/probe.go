@@ -0,0 +1,42 @@
+package handlers
+
+import (
+	"net/http"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+	"go.uber.org/zap"
+)
+
+// ProbeFunc builds the collectors for the target requested by a /probe call.
+// Collectors should be bound to the request's context, which is cancelled
+// when the scrape times out.
+type ProbeFunc func(r *http.Request) ([]prometheus.Collector, error)
+
+// ProbeHandler exports the metrics of a single target into a throwaway registry,
+// the same way blackbox_exporter probes work.
+func ProbeHandler(fn ProbeFunc) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		ctx, cancel := ScrapeContext(r)
+		defer cancel()
+		r = r.WithContext(ctx)
+
+		collectors, err := fn(r)
+		if err != nil {
+			zap.S().Debugw("Invalid probe request",
+				"url", r.URL,
+				"error", err)
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+
+		registry := prometheus.NewRegistry()
+		for _, c := range collectors {
+			if err := registry.Register(c); err != nil {
+				http.Error(w, err.Error(), http.StatusInternalServerError)
+				return
+			}
+		}
+		promhttp.HandlerFor(registry, promhttp.HandlerOpts{}).ServeHTTP(w, r)
+	})
+}
@@ -0,0 +1,26 @@
+package handlers
+
+import (
+	"context"
+	"net/http"
+	"strconv"
+	"time"
+)
+
+// Time kept back from the scrape timeout Prometheus sends, so the exporter can
+// still respond with whatever it collected before Prometheus gives up.
+var SCRAPE_TIMEOUT_OFFSET = 500 * time.Millisecond
+
+// ScrapeContext returns the request's context, cancelled shortly before the
+// scrape timeout from the X-Prometheus-Scrape-Timeout-Seconds header when set.
+func ScrapeContext(r *http.Request) (context.Context, context.CancelFunc) {
+	seconds, err := strconv.ParseFloat(r.Header.Get("X-Prometheus-Scrape-Timeout-Seconds"), 64)
+	if err != nil || seconds <= 0 {
+		return context.WithCancel(r.Context())
+	}
+	timeout := time.Duration(seconds * float64(time.Second))
+	if timeout > SCRAPE_TIMEOUT_OFFSET {
+		timeout -= SCRAPE_TIMEOUT_OFFSET
+	}
+	return context.WithTimeout(r.Context(), timeout)
+}
@@ -1,13 +1,14 @@
 package collector
 
 import (
+	"context"
 	"fmt"
-	"time"
 
 	"github.com/prometheus/client_golang/prometheus"
 	"go.uber.org/zap"
 
 	"github.com/onedr0p/exportarr/internal/client"
+	base_config "github.com/onedr0p/exportarr/internal/config"
 	"github.com/onedr0p/exportarr/internal/sabnzbd/auth"
 	"github.com/onedr0p/exportarr/internal/sabnzbd/config"
 	"github.com/onedr0p/exportarr/internal/sabnzbd/model"
@@ -143,18 +144,6 @@ var (
 		[]string{"url"},
 		nil,
 	)
-	queueQueryDuration = prometheus.NewDesc(
-		prometheus.BuildFQName(METRIC_PREFIX, "", "queue_query_duration_seconds"),
-		"Duration querying the queue endpoint of SabnzbD",
-		[]string{"url"},
-		nil,
-	)
-	serverStatsQueryDuration = prometheus.NewDesc(
-		prometheus.BuildFQName(METRIC_PREFIX, "", "server_stats_query_duration_seconds"),
-		"Duration querying the server_stats endpoint of SabnzbD",
-		[]string{"url"},
-		nil,
-	)
 )
 
 func boolToFloat(b bool) float64 {
@@ -173,10 +162,11 @@ type SabnzbdCollector struct {
 
 // TODO: Add a sab-specific config struct to abstract away the config parsing
 func NewSabnzbdCollector(config *config.SabnzbdConfig) (*SabnzbdCollector, error) {
-	auther := auth.ApiKeyAuth{ApiKey: config.ApiKey}
-	client, err := client.NewClient(config.URL, config.DisableSSLVerify, auther, config.ApiRootPath)
+	client, err := config.Clients.Get(config, func() (*client.Client, error) {
+		return newClient(config)
+	})
 	if err != nil {
-		return nil, fmt.Errorf("Failed to build client: %w", err)
+		return nil, err
 	}
 
 	println("ApiRootPath: " + config.ApiRootPath)
@@ -188,16 +178,41 @@ func NewSabnzbdCollector(config *config.SabnzbdConfig) (*SabnzbdCollector, error
 	}, nil
 }
 
-func (s *SabnzbdCollector) doRequest(mode string, target interface{}) error {
+// newClient builds the client of SABnzbd described by config.
+func newClient(config *config.SabnzbdConfig) (*client.Client, error) {
+	tlsConfig, err := config.TLS.Build()
+	if err != nil {
+		return nil, err
+	}
+	proxy, err := base_config.ParseProxyURL(config.ProxyURL)
+	if err != nil {
+		return nil, err
+	}
+	opts := client.TransportOptions{TLS: tlsConfig, Proxy: proxy, Retry: config.RetryPolicy}
+	auther := client.Chain(auth.ApiKeyAuth{ApiKey: config.ApiKey}, client.ProxyAuth(config.Headers, client.OAuth2Config{
+		TokenURL:     config.OAuth2TokenURL,
+		ClientID:     config.OAuth2ClientID,
+		ClientSecret: config.OAuth2Secret,
+		Scopes:       config.OAuth2Scopes,
+	}, opts))
+	c, err := client.NewClient(config.URL, opts, auther, config.ApiRootPath)
+	if err != nil {
+		return nil, fmt.Errorf("Failed to build client: %w", err)
+	}
+	c.App = "sabnzbd"
+	return c, nil
+}
+
+func (s *SabnzbdCollector) doRequest(ctx context.Context, mode string, target interface{}) error {
 	params := client.QueryParams{}
 	params.Add("mode", mode)
-	return s.client.DoRequest("/api", target, params)
+	return s.client.DoRequest(ctx, "/api", target, params)
 }
 
-func (s *SabnzbdCollector) getQueueStats() (*model.QueueStats, error) {
+func (s *SabnzbdCollector) getQueueStats(ctx context.Context) (*model.QueueStats, error) {
 	var stats = &model.QueueStats{}
 
-	err := s.doRequest("queue", stats)
+	err := s.doRequest(ctx, "queue", stats)
 	if err != nil {
 		return nil, fmt.Errorf("Failed to get queue stats: %w", err)
 	}
@@ -205,9 +220,9 @@ func (s *SabnzbdCollector) getQueueStats() (*model.QueueStats, error) {
 	return stats, nil
 }
 
-func (s *SabnzbdCollector) getServerStats() (*model.ServerStats, error) {
+func (s *SabnzbdCollector) getServerStats(ctx context.Context) (*model.ServerStats, error) {
 	var stats = &model.ServerStats{}
-	err := s.doRequest("server_stats", stats)
+	err := s.doRequest(ctx, "server_stats", stats)
 	if err != nil {
 		return nil, fmt.Errorf("Failed to get server stats: %w", err)
 	}
@@ -236,27 +251,23 @@ func (e *SabnzbdCollector) Describe(ch chan<- *prometheus.Desc) {
 	ch <- serverArticlesTotal
 	ch <- serverArticlesSuccess
 	ch <- warnings
-	ch <- queueQueryDuration
-	ch <- serverStatsQueryDuration
 }
 
 func (e *SabnzbdCollector) Collect(ch chan<- prometheus.Metric) {
+	e.CollectContext(context.Background(), ch)
+}
+
+func (e *SabnzbdCollector) CollectContext(ctx context.Context, ch chan<- prometheus.Metric) {
 	log := zap.S().With("collector", "sabnzbd")
 
 	queueStats := &model.QueueStats{}
 	serverStats := &model.ServerStats{}
 
-	g := new(errgroup.Group)
+	g, ctx := errgroup.WithContext(ctx)
 
 	g.Go(func() error {
-		qStart := time.Now()
-		defer func() { //nolint:wsl
-			ch <- prometheus.MustNewConstMetric(
-				queueQueryDuration, prometheus.GaugeValue, time.Since(qStart).Seconds(), e.baseURL)
-		}()
-
 		var err error
-		queueStats, err = e.getQueueStats()
+		queueStats, err = e.getQueueStats(ctx)
 		if err != nil {
 			log.Errorw("Failed to get queue stats", "error", err)
 			return fmt.Errorf("failed to get queue stats: %w", err)
@@ -265,14 +276,8 @@ func (e *SabnzbdCollector) Collect(ch chan<- prometheus.Metric) {
 	})
 
 	g.Go(func() error {
-		sStart := time.Now()
-		defer func() { //nolint:wsl
-			ch <- prometheus.MustNewConstMetric(
-				serverStatsQueryDuration, prometheus.GaugeValue, time.Since(sStart).Seconds(), e.baseURL)
-		}()
-
 		var err error
-		serverStats, err = e.getServerStats()
+		serverStats, err = e.getServerStats(ctx)
 		if err != nil {
 			log.Errorw("Failed to get server stats", "error", err)
 			return fmt.Errorf("failed to get server stats: %w", err)
@@ -1,14 +1,20 @@
 package collector
 
 import (
+	"fmt"
 	"net/http"
 	"net/http/httptest"
+	"net/url"
 	"os"
+	"path/filepath"
 	"strings"
 	"testing"
 
+	"github.com/onedr0p/exportarr/internal/client"
+	base_config "github.com/onedr0p/exportarr/internal/config"
 	"github.com/onedr0p/exportarr/internal/sabnzbd/config"
 	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/spf13/pflag"
 	"github.com/stretchr/testify/require"
 )
 
@@ -116,3 +122,59 @@ func TestCollect_FailureDoesntPanic(t *testing.T) {
 	}, "Collecting metrics should not panic on failure")
 	require.Error(err)
 }
+
+func TestNewSabnzbdCollector_Pool(t *testing.T) {
+	require := require.New(t)
+
+	config := &config.SabnzbdConfig{
+		URL:         "http://localhost:8080",
+		ApiKey:      API_KEY,
+		ApiRootPath: "/sabnzbd",
+		Clients:     client.NewPool(),
+	}
+	first, err := NewSabnzbdCollector(config)
+	require.NoError(err)
+	second, err := NewSabnzbdCollector(config)
+	require.NoError(err)
+	require.Same(first.client, second.client, "the client of a pooled config should be reused")
+
+	config.Clients.Close()
+	third, err := NewSabnzbdCollector(config)
+	require.NoError(err)
+	require.NotSame(first.client, third.client, "the clients of a closed pool should be dropped")
+}
+
+func TestCollect_INIConfig(t *testing.T) {
+	require := require.New(t)
+	var paths []string
+	ts, err := newTestServer(t, func(w http.ResponseWriter, r *http.Request) {
+		paths = append(paths, r.URL.Path)
+	})
+	require.NoError(err)
+	defer ts.Close()
+
+	u, err := url.Parse(ts.URL)
+	require.NoError(err)
+	ini := filepath.Join(t.TempDir(), "sabnzbd.ini")
+	require.NoError(os.WriteFile(ini, []byte(fmt.Sprintf(`[misc]
+host = 127.0.0.1
+port = %s
+api_key = %s
+url_base = /sabnzbd
+`, u.Port(), API_KEY)), 0600))
+
+	flags := pflag.NewFlagSet("test", pflag.ContinueOnError)
+	config.RegisterSabnzbdFlags(flags)
+	require.NoError(flags.Set("config", ini))
+	// ApiRootPath defaults to /sabnzbd, which url_base replaces
+	c, err := config.LoadSabnzbdConfig(base_config.Config{}, flags)
+	require.NoError(err)
+	collector, err := NewSabnzbdCollector(c)
+	require.NoError(err)
+
+	testutil.CollectAndCount(collector)
+	require.NotEmpty(paths)
+	for _, path := range paths {
+		require.Equal("/sabnzbd/api", path)
+	}
+}
@@ -2,33 +2,114 @@ package config
 
 import (
 	"github.com/gookit/validate"
+	"github.com/knadh/koanf/providers/file"
+	"github.com/knadh/koanf/providers/posflag"
+	"github.com/knadh/koanf/v2"
+	flag "github.com/spf13/pflag"
+
+	"github.com/onedr0p/exportarr/internal/client"
 	base_config "github.com/onedr0p/exportarr/internal/config"
 )
 
+func RegisterSabnzbdFlags(flags *flag.FlagSet) {
+	flags.StringP("config", "c", "", "SABnzbd sabnzbd.ini file for parsing the API key, port and URL base")
+}
+
 type SabnzbdConfig struct {
-	URL              string `validate:"required|url"`
-	ApiKey           string `validate:"required"`
-	DisableSSLVerify bool
-	ApiRootPath      string 
+	URL            string `validate:"required|url"`
+	ApiKey         string `validate:"required"`
+	INIConfig      string
+	TLS            base_config.TLSConfig
+	ProxyURL       string
+	Headers        map[string]string
+	OAuth2TokenURL string
+	OAuth2ClientID string
+	OAuth2Secret   string
+	OAuth2Scopes   []string
+	ApiRootPath    string
+	RetryPolicy    *client.RetryPolicy // nil to use client.DefaultRetryPolicy
+	Clients        *client.Pool        // holds the client of the collector, nil for throwaway configs
 }
 
-func LoadSabnzbdConfig(conf base_config.Config) (*SabnzbdConfig, error) {
+// LoadSabnzbdConfig builds the config of SABnzbd from the base config. When
+// the config setting names SABnzbd's sabnzbd.ini, in the config file, the
+// environment or flags, its API key and URL are used, and its url_base, which
+// ends up in the URL, is the API root instead of api-root-path. flags may be nil.
+func LoadSabnzbdConfig(conf base_config.Config, flags *flag.FlagSet) (*SabnzbdConfig, error) {
 	if conf.ApiRootPath == "" {
 		conf.ApiRootPath = "/sabnzbd"
 	}
 	ret := &SabnzbdConfig{
-		URL:              conf.URL,
-		ApiKey:           conf.ApiKey,
-		DisableSSLVerify: conf.DisableSSLVerify,
-		ApiRootPath:      conf.ApiRootPath,
+		URL:            conf.URL,
+		ApiKey:         conf.ApiKey,
+		TLS:            conf.TLS(),
+		ProxyURL:       conf.ProxyURL,
+		Headers:        conf.Headers,
+		OAuth2TokenURL: conf.OAuth2TokenURL,
+		OAuth2ClientID: conf.OAuth2ClientID,
+		OAuth2Secret:   conf.OAuth2Secret,
+		OAuth2Scopes:   conf.OAuth2Scopes,
+		ApiRootPath:    conf.ApiRootPath,
+		RetryPolicy:    conf.RetryPolicy(),
+	}
+	if flags == nil {
+		return ret, nil
+	}
+
+	k := koanf.New(".")
+	if f := conf.File(); f != nil {
+		if err := k.Merge(f.Koanf()); err != nil {
+			return nil, err
+		}
+		f.ShadowFlags(flags)
+	}
+	envK, deprecatedEnv, err := base_config.LoadEnv(base_config.EnvOptions{
+		App:    conf.App,
+		Keys:   []string{"config"},
+		Legacy: !conf.DisableLegacyEnv,
+	})
+	if err != nil {
+		return nil, err
+	}
+	base_config.LogDeprecatedEnv(deprecatedEnv)
+	if err := k.Merge(envK); err != nil {
+		return nil, err
+	}
+	if err := k.Load(posflag.Provider(flags, ".", k), nil); err != nil {
+		return nil, err
+	}
+
+	ret.INIConfig = k.String("config")
+	if ret.INIConfig != "" {
+		ini := koanf.New(".")
+		err := ini.Load(file.Provider(ret.INIConfig), INIParser(), koanf.WithMergeFunc(base_config.MergeAppConfig(conf.URL)))
+		if err != nil {
+			return nil, err
+		}
+		if ini.String("api-key") != "" {
+			ret.ApiKey = ini.String("api-key")
+		}
+		ret.URL = ini.String("url")
+		ret.ApiRootPath = "/"
 	}
 	return ret, nil
 }
 
+// Files returns the files SABnzbd's settings were read from, beyond those of the base config.
+func (c *SabnzbdConfig) Files() []string {
+	if c.INIConfig == "" {
+		return nil
+	}
+	return []string{c.INIConfig}
+}
+
 func (c *SabnzbdConfig) Validate() error {
 	v := validate.Struct(c)
 	if !v.Validate() {
 		return v.Errors
 	}
-	return nil
+	if err := base_config.ValidateUpstream(c.URL, c.ProxyURL); err != nil {
+		return err
+	}
+	return c.TLS.Validate()
 }
@@ -0,0 +1,46 @@
+package config
+
+import (
+	"testing"
+
+	"github.com/spf13/pflag"
+	"github.com/stretchr/testify/require"
+
+	base_config "github.com/onedr0p/exportarr/internal/config"
+)
+
+func TestLoadSabnzbdConfig_INIConfig(t *testing.T) {
+	require := require.New(t)
+	flags := pflag.NewFlagSet("test", pflag.ContinueOnError)
+	RegisterSabnzbdFlags(flags)
+	flags.Set("config", "test_fixtures/sabnzbd.ini")
+
+	c, err := LoadSabnzbdConfig(base_config.Config{URL: "http://sabnzbd"}, flags)
+	require.NoError(err)
+	require.Equal("https://sabnzbd:9090/sabnzbd", c.URL)
+	require.Equal("/", c.ApiRootPath, "url_base should be the API root")
+	require.Equal("abcdef0123456789abcdef0123456789", c.ApiKey)
+	require.Equal([]string{"test_fixtures/sabnzbd.ini"}, c.Files())
+	require.NoError(c.Validate())
+
+	// Without a url, SABnzbd is reached at the address it listens on
+	c, err = LoadSabnzbdConfig(base_config.Config{}, flags)
+	require.NoError(err)
+	require.Equal("https://localhost:9090/sabnzbd", c.URL)
+}
+
+func TestLoadSabnzbdConfig_Env(t *testing.T) {
+	require := require.New(t)
+	t.Setenv("EXPORTARR_CONFIG", "test_fixtures/sabnzbd.ini")
+	flags := pflag.NewFlagSet("test", pflag.ContinueOnError)
+	RegisterSabnzbdFlags(flags)
+
+	c, err := LoadSabnzbdConfig(base_config.Config{App: "sabnzbd", URL: "http://sabnzbd"}, flags)
+	require.NoError(err)
+	require.Equal("abcdef0123456789abcdef0123456789", c.ApiKey)
+
+	// Targets of serve read their config file themselves
+	c, err = LoadSabnzbdConfig(base_config.Config{URL: "http://sabnzbd", ApiKey: "key"}, nil)
+	require.NoError(err)
+	require.Equal("key", c.ApiKey)
+}
@@ -0,0 +1,35 @@
+package config
+
+import (
+	"errors"
+	"fmt"
+
+	base_config "github.com/onedr0p/exportarr/internal/config"
+)
+
+// INI parses the settings of SABnzbd's sabnzbd.ini needed to reach its API,
+// see base_config.MergeAppConfig.
+type INI struct{}
+
+func INIParser() *INI {
+	return &INI{}
+}
+
+func (p *INI) Unmarshal(b []byte) (map[string]interface{}, error) {
+	misc := base_config.ParseINI(b)["misc"]
+	if misc == nil {
+		return nil, fmt.Errorf("no [misc] section found")
+	}
+	return map[string]interface{}{
+		"api-key":      misc["api_key"],
+		"url-base":     misc["url_base"],
+		"target-port":  misc["port"],
+		"ssl-port":     misc["https_port"],
+		"enable-ssl":   misc["enable_https"] == "1",
+		"bind-address": misc["host"],
+	}, nil
+}
+
+func (p *INI) Marshal(o map[string]interface{}) ([]byte, error) {
+	return nil, errors.New("not implemented")
+}